@@ -0,0 +1,111 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/rasha-hantash/chariot-takehome/api/grpc/repository"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/currency"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/pagetoken"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the ErrorInfo detail attached to every error status
+const ErrorDomain = "payments.api"
+
+// errorMappings maps domain errors to the status code and machine-readable reason they are
+// reported with. The first matching entry wins, so more specific errors come first.
+var errorMappings = []struct {
+	err    error
+	code   codes.Code
+	reason string
+}{
+	{repository.ErrInsufficientFunds, codes.FailedPrecondition, "INSUFFICIENT_FUNDS"},
+	{repository.ErrAccountFrozen, codes.FailedPrecondition, "ACCOUNT_FROZEN"},
+	{repository.ErrAccountClosed, codes.FailedPrecondition, "ACCOUNT_CLOSED"},
+	{repository.ErrAccountNotEmpty, codes.FailedPrecondition, "ACCOUNT_NOT_EMPTY"},
+	{repository.ErrInvalidStateTransition, codes.FailedPrecondition, "INVALID_STATE_TRANSITION"},
+	{repository.ErrHoldNotPending, codes.FailedPrecondition, "HOLD_NOT_PENDING"},
+	{repository.ErrNotReversible, codes.FailedPrecondition, "NOT_REVERSIBLE"},
+	{repository.ErrUserDeactivated, codes.FailedPrecondition, "USER_DEACTIVATED"},
+	{repository.ErrWebhookDeliveryPending, codes.FailedPrecondition, "WEBHOOK_DELIVERY_PENDING"},
+	{repository.ErrPaymentMethodNotVerified, codes.FailedPrecondition, "PAYMENT_METHOD_NOT_VERIFIED"},
+	{repository.ErrAccountNotFound, codes.NotFound, "ACCOUNT_NOT_FOUND"},
+	{repository.ErrTransactionNotFound, codes.NotFound, "TRANSACTION_NOT_FOUND"},
+	{repository.ErrHoldNotFound, codes.NotFound, "HOLD_NOT_FOUND"},
+	{repository.ErrUserNotFound, codes.NotFound, "USER_NOT_FOUND"},
+	{repository.ErrWebhookEndpointNotFound, codes.NotFound, "WEBHOOK_ENDPOINT_NOT_FOUND"},
+	{repository.ErrWebhookDeliveryNotFound, codes.NotFound, "WEBHOOK_DELIVERY_NOT_FOUND"},
+	{repository.ErrPaymentMethodNotFound, codes.NotFound, "PAYMENT_METHOD_NOT_FOUND"},
+	{repository.ErrAccountNotOwned, codes.PermissionDenied, "ACCOUNT_NOT_OWNED"},
+	{repository.ErrPaymentMethodNotOwned, codes.PermissionDenied, "PAYMENT_METHOD_NOT_OWNED"},
+	{repository.ErrWebhookEndpointNotOwned, codes.PermissionDenied, "WEBHOOK_ENDPOINT_NOT_OWNED"},
+	{repository.ErrDuplicateEmail, codes.AlreadyExists, "DUPLICATE_EMAIL"},
+	{repository.ErrIdempotencyKeyReused, codes.AlreadyExists, "IDEMPOTENCY_KEY_REUSED"},
+	{repository.ErrIdempotencyKeyInProgress, codes.Aborted, "IDEMPOTENCY_KEY_IN_PROGRESS"},
+	{repository.ErrConcurrentUpdate, codes.Aborted, "CONCURRENT_UPDATE"},
+	{repository.ErrRetriesExhausted, codes.Aborted, "TRANSACTION_CONFLICT"},
+	{money.ErrCurrencyMismatch, codes.InvalidArgument, "CURRENCY_MISMATCH"},
+	{currency.ErrUnsupported, codes.InvalidArgument, "UNSUPPORTED_CURRENCY"},
+	{money.ErrInvalidAmount, codes.InvalidArgument, "INVALID_AMOUNT"},
+	{money.ErrNegativeAmount, codes.InvalidArgument, "INVALID_AMOUNT"},
+	{money.ErrSubMinorUnit, codes.InvalidArgument, "INVALID_AMOUNT"},
+	{repository.ErrInvalidAccountState, codes.InvalidArgument, "INVALID_ACCOUNT_STATE"},
+	{repository.ErrInvalidRequest, codes.InvalidArgument, "INVALID_REQUEST"},
+	{repository.ErrInvalidPosting, codes.InvalidArgument, "INVALID_POSTING"},
+	{pagetoken.ErrInvalid, codes.InvalidArgument, "INVALID_PAGE_TOKEN"},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, "DEADLINE_EXCEEDED"},
+	{context.Canceled, codes.Canceled, "CANCELED"},
+}
+
+// ErrorUnaryServerInterceptor converts errors returned by the service into gRPC statuses
+// with an ErrorInfo detail carrying a machine-readable reason. Errors that are not domain
+// errors are logged and reported as internal errors without their details.
+func ErrorUnaryServerInterceptor() grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, toStatusError(ctx, err)
+		}
+		return resp, nil
+	}
+}
+
+// ErrorStreamServerInterceptor is ErrorUnaryServerInterceptor for streaming RPCs
+func ErrorStreamServerInterceptor() grpclib.StreamServerInterceptor {
+	return func(srv interface{}, ss grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+		if err := handler(srv, ss); err != nil {
+			return toStatusError(ss.Context(), err)
+		}
+		return nil
+	}
+}
+
+// toStatusError converts an error into a gRPC status error
+func toStatusError(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			return statusWithReason(m.code, m.reason, err.Error())
+		}
+	}
+
+	slog.ErrorContext(ctx, "internal error", "error", err)
+	return statusWithReason(codes.Internal, "INTERNAL", "internal error")
+}
+
+func statusWithReason(code codes.Code, reason, msg string) error {
+	st, err := status.New(code, msg).WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: ErrorDomain})
+	if err != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
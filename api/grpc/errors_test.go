@@ -0,0 +1,100 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/rasha-hantash/chariot-takehome/api/grpc/repository"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatusError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   codes.Code
+		wantReason string
+	}{
+		{
+			name:       "insufficient funds",
+			err:        fmt.Errorf("%w in account acct_1", repository.ErrInsufficientFunds),
+			wantCode:   codes.FailedPrecondition,
+			wantReason: "INSUFFICIENT_FUNDS",
+		},
+		{
+			name:       "account not found behind another wrap",
+			err:        fmt.Errorf("error checking balance: %w", fmt.Errorf("%w: acct_1", repository.ErrAccountNotFound)),
+			wantCode:   codes.NotFound,
+			wantReason: "ACCOUNT_NOT_FOUND",
+		},
+		{
+			name:       "account frozen",
+			err:        fmt.Errorf("%w: account acct_1 cannot be debited", repository.ErrAccountFrozen),
+			wantCode:   codes.FailedPrecondition,
+			wantReason: "ACCOUNT_FROZEN",
+		},
+		{
+			name:       "state transition is not an invalid state",
+			err:        fmt.Errorf("%w: account acct_1 cannot move from closed to open", repository.ErrInvalidStateTransition),
+			wantCode:   codes.FailedPrecondition,
+			wantReason: "INVALID_STATE_TRANSITION",
+		},
+		{
+			name:       "duplicate email",
+			err:        fmt.Errorf("%w: a@example.com", repository.ErrDuplicateEmail),
+			wantCode:   codes.AlreadyExists,
+			wantReason: "DUPLICATE_EMAIL",
+		},
+		{
+			name:       "invalid amount",
+			err:        fmt.Errorf("%w: amount is required", money.ErrInvalidAmount),
+			wantCode:   codes.InvalidArgument,
+			wantReason: "INVALID_AMOUNT",
+		},
+		{
+			name:       "transaction retries exhausted",
+			err:        fmt.Errorf("%w: failed after 5 attempts: %w", repository.ErrRetriesExhausted, &pq.Error{Code: "40001"}),
+			wantCode:   codes.Aborted,
+			wantReason: "TRANSACTION_CONFLICT",
+		},
+		{
+			name:       "concurrent update that exhausted its retries",
+			err:        fmt.Errorf("%w: failed after 5 attempts: %w", repository.ErrRetriesExhausted, repository.ErrConcurrentUpdate),
+			wantCode:   codes.Aborted,
+			wantReason: "CONCURRENT_UPDATE",
+		},
+		{
+			name:       "unexpected error",
+			err:        errors.New("pq: connection refused"),
+			wantCode:   codes.Internal,
+			wantReason: "INTERNAL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(toStatusError(context.Background(), tt.err))
+			assert.Equal(t, tt.wantCode, st.Code())
+			if assert.Len(t, st.Details(), 1) {
+				assert.Equal(t, tt.wantReason, st.Details()[0].(*errdetails.ErrorInfo).Reason)
+			}
+		})
+	}
+
+	t.Run("internal errors do not leak details", func(t *testing.T) {
+		st := status.Convert(toStatusError(context.Background(), errors.New("pq: connection refused")))
+		assert.Equal(t, "internal error", st.Message())
+	})
+
+	t.Run("status errors pass through", func(t *testing.T) {
+		err := status.Error(codes.Unauthenticated, "no token")
+		assert.Equal(t, err, toStatusError(context.Background(), err))
+	})
+}
@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/grpc/repository"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// idempotentRequest is a request that carries an idempotency key on behalf of a user
+type idempotentRequest interface {
+	proto.Message
+	GetIdempotencyKey() string
+	GetUserId() string
+}
+
+// IdempotencyUnaryServerInterceptor makes requests that carry an idempotency key safe to
+// retry. The first request with a key runs and its response is stored; a retry with the
+// same key and payload gets the stored response without running again, and reusing a key
+// with a different payload is rejected. Keys are scoped to the requesting user and expire
+// after ttl. A key whose request has not committed anything lapses after inProgressTTL, so a
+// request that died in flight does not block its retries for long. Requests without a key are
+// not affected.
+func IdempotencyUnaryServerInterceptor(keys *repository.IdempotencyRepository, ttl, inProgressTTL time.Duration) grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+		r, ok := req.(idempotentRequest)
+		if !ok || r.GetIdempotencyKey() == "" {
+			return handler(ctx, req)
+		}
+		caller, key := r.GetUserId(), r.GetIdempotencyKey()
+
+		fingerprint, err := requestFingerprint(info.FullMethod, r)
+		if err != nil {
+			return nil, err
+		}
+
+		record, err := keys.Claim(ctx, caller, key, info.FullMethod, fingerprint, inProgressTTL)
+		if err != nil {
+			return nil, err
+		}
+
+		if record.Response != nil {
+			slog.InfoContext(ctx, "replaying idempotent response", "idempotency_key", key)
+			var stored anypb.Any
+			if err := proto.Unmarshal(record.Response, &stored); err != nil {
+				return nil, err
+			}
+			return stored.UnmarshalNew()
+		}
+
+		// The key is marked as used in the same transaction that commits the request's changes
+		resp, err := handler(repository.WithIdempotencyClaim(ctx, record, ttl), req)
+		// The key is released or completed even if the caller has gone away, so that it is not
+		// left in progress
+		storeCtx := context.WithoutCancel(ctx)
+		if err != nil {
+			// Failed requests that committed nothing do not consume the key so that they can be retried
+			if releaseErr := keys.Release(storeCtx, record); releaseErr != nil {
+				slog.ErrorContext(ctx, "error releasing idempotency key", "idempotency_key", key, "error", releaseErr)
+			}
+			return nil, err
+		}
+
+		stored, err := anypb.New(resp.(proto.Message))
+		if err == nil {
+			var response []byte
+			response, err = proto.Marshal(stored)
+			if err == nil {
+				err = keys.Complete(storeCtx, record, response, ttl)
+			}
+		}
+		if err != nil {
+			// The request has already succeeded and its key was marked as used when it
+			// committed, so retries are rejected until the key expires rather than run again.
+			slog.ErrorContext(ctx, "error storing idempotent response", "idempotency_key", key, "error", err)
+		}
+		return resp, nil
+	}
+}
+
+// requestFingerprint hashes a request together with the method it was sent to
+func requestFingerprint(method string, req proto.Message) ([]byte, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write(b)
+	return h.Sum(nil), nil
+}
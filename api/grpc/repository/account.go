@@ -3,16 +3,58 @@ package repository
 import (
 	"context"
 	"database/sql"
-	"log/slog"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/currency"
 	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
 )
 
 type Account struct {
-	Id           string
+	Id string
+	// UserId is the user the account belongs to, if any
+	UserId       string
 	AccountState string
 	AccountType  string
+	Currency     string
+	// OverdraftLimit is how far below zero the available balance may go, in minor units
+	OverdraftLimit int64
+	// UnlimitedOverdraft lets the account go negative without limit, e.g. external accounts
+	UnlimitedOverdraft bool
+	CreatedAt          time.Time
+	// Balance is the account's current balance; it is only loaded by GetAccount and ListAccounts
+	Balance *Balance
+}
+
+// AccountFilter narrows the accounts listed by ListAccounts. Empty fields match every account.
+type AccountFilter struct {
+	UserId       string
+	AccountType  string
+	AccountState string
+	// Cursor is the ID of the last account on the previous page
+	Cursor string
+	Limit  int
+}
+
+// DefaultAccountPageSize is the number of accounts listed when no limit is given
+const DefaultAccountPageSize = 50
+
+// Balance is the balance of an account in its own currency as of a point in time. Amount is
+// the posted balance, Pending is reserved by holds and Available is what can be spent.
+type Balance struct {
+	AccountId string
+	Amount    money.Money
+	Pending   money.Money
+	Available money.Money
+	AsOf      time.Time
+	// OverdraftLimit and UnlimitedOverdraft are the account's current limit, even for a
+	// historical balance
+	OverdraftLimit     money.Money
+	UnlimitedOverdraft bool
 }
 
 type AccountRepository struct {
@@ -25,11 +67,45 @@ func NewAccountRepository(db *sql.DB, prefix string) *AccountRepository {
 }
 
 func (a *AccountRepository) CreateAccount(ctx context.Context, account *Account) (string, error) {
+	return a.CreateAccountTx(ctx, a.db, account)
+}
+
+// CreateAccountTx creates an account using q, which lets the account be created in the same
+// transaction as other writes. The account's ID is generated unless it is already set.
+func (a *AccountRepository) CreateAccountTx(ctx context.Context, q Querier, account *Account) (string, error) {
 	var id string
-	hrId := a.ID.New()
+	hrId := account.Id
+	if hrId == "" {
+		hrId = string(a.ID.New())
+	}
 
-	err := a.db.QueryRowContext(ctx, "INSERT INTO accounts (id, account_state, account_type) VALUES ($1, $2, $3) RETURNING id",
-		hrId, account.AccountState, account.AccountType).Scan(&id)
+	if account.Currency == "" {
+		account.Currency = currency.Default
+	}
+	cur, err := currency.Lookup(account.Currency)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while creating account", "error", err)
+		return "", err
+	}
+	account.AccountState, err = validAccountState(account.AccountState)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while creating account", "error", err)
+		return "", err
+	}
+
+	if account.OverdraftLimit < 0 {
+		return "", fmt.Errorf("%w: overdraft limit cannot be negative", money.ErrNegativeAmount)
+	}
+
+	if account.UserId != "" {
+		if err := checkAccountOwner(ctx, q, account.UserId); err != nil {
+			return "", err
+		}
+	}
+
+	err = q.QueryRowContext(ctx, "INSERT INTO accounts (id, user_id, account_state, account_type, currency, overdraft_limit) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at",
+		hrId, sql.NullString{String: account.UserId, Valid: account.UserId != ""}, account.AccountState, account.AccountType, cur.Code,
+		overdraftLimit(account.OverdraftLimit, account.UnlimitedOverdraft)).Scan(&id, &account.CreatedAt)
 	if err != nil {
 		slog.ErrorContext(ctx, "error while creating account", "error", err)
 		return "", err
@@ -37,14 +113,246 @@ func (a *AccountRepository) CreateAccount(ctx context.Context, account *Account)
 	return id, err
 }
 
-func (a *AccountRepository) GetAccountBalance(ctx context.Context, accountId string) (int64, error) {
-	var balance int64
-	fmt.Println("accountId", accountId)
-	err := a.db.QueryRowContext(ctx, "SELECT COALESCE(SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END), 0) as balance FROM ledger_entries WHERE account_id = $1", accountId).Scan(&balance)
+// checkAccountOwner checks that an account can be opened for a user, who must exist and be
+// active. The user is locked so that they cannot be deactivated while the account is created.
+func checkAccountOwner(ctx context.Context, q Querier, userId string) error {
+	var state string
+	err := q.QueryRowContext(ctx, "SELECT user_state FROM users WHERE id = $1 FOR SHARE", userId).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrUserNotFound, userId)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading account owner: %w", err)
+	}
+	if state != UserStateActive {
+		return fmt.Errorf("%w: %s", ErrUserDeactivated, userId)
+	}
+	return nil
+}
+
+// selectAccounts reads accounts along with their current balance from the projection
+const selectAccounts = `
+	SELECT a.id, COALESCE(a.user_id, ''), a.account_type, a.account_state, a.currency, a.overdraft_limit, a.created_at,
+		COALESCE(b.posted, 0), COALESCE(b.pending, 0), COALESCE(b.available, 0), CURRENT_TIMESTAMP
+	FROM accounts a
+	LEFT JOIN account_balances b ON b.account_id = a.id
+`
+
+func scanAccount(row rowScanner) (*Account, error) {
+	var account Account
+	var limit sql.NullInt64
+	balance := &Balance{}
+	err := row.Scan(&account.Id, &account.UserId, &account.AccountType, &account.AccountState, &account.Currency, &limit, &account.CreatedAt,
+		&balance.Amount.Units, &balance.Pending.Units, &balance.Available.Units, &balance.AsOf)
+	if err != nil {
+		return nil, err
+	}
+	account.OverdraftLimit = limit.Int64
+	account.UnlimitedOverdraft = !limit.Valid
+
+	balance.AccountId = account.Id
+	balance.AsOf = balance.AsOf.UTC()
+	balance.setOverdraft(limit)
+	balance.setCurrency(account.Currency)
+	account.Balance = balance
+	return &account, nil
+}
+
+// GetAccount returns an account with its current balance
+func (a *AccountRepository) GetAccount(ctx context.Context, accountId string) (*Account, error) {
+	account, err := scanAccount(a.db.QueryRowContext(ctx, selectAccounts+"WHERE a.id = $1", accountId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountId)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error while getting account", "error", err)
+		return nil, err
+	}
+	return account, nil
+}
+
+// ListAccounts lists the accounts matching filter in ID order, with their current balances.
+// The returned cursor is empty on the last page.
+func (a *AccountRepository) ListAccounts(ctx context.Context, filter AccountFilter) ([]Account, string, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultAccountPageSize
+	}
+
+	var args []interface{}
+	var conditions []string
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.UserId != "" {
+		addCondition("a.user_id = $%d", filter.UserId)
+	}
+	if filter.AccountType != "" {
+		addCondition("a.account_type = $%d", filter.AccountType)
+	}
+	if filter.AccountState != "" {
+		addCondition("a.account_state = $%d", filter.AccountState)
+	}
+	if filter.Cursor != "" {
+		addCondition("a.id > $%d", filter.Cursor)
+	}
+
+	query := selectAccounts
+	if len(conditions) > 0 {
+		query += "WHERE " + strings.Join(conditions, " AND ")
+	}
+	// One extra row tells whether there is another page
+	args = append(args, limit+1)
+	query += fmt.Sprintf(" ORDER BY a.id LIMIT $%d", len(args))
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while listing accounts", "error", err)
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var accounts []Account
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("error scanning account: %w", err)
+		}
+		accounts = append(accounts, *account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating accounts: %w", err)
+	}
+
+	var nextCursor string
+	if len(accounts) > limit {
+		accounts = accounts[:limit]
+		nextCursor = accounts[limit-1].Id
+	}
+	return accounts, nextCursor, nil
+}
+
+// SetOverdraftLimit sets how far below zero an account's available balance may go. A nil
+// limit lets the account go negative without limit. Lowering the limit below what is
+// already overdrawn only blocks further debits.
+func (a *AccountRepository) SetOverdraftLimit(ctx context.Context, accountId string, limit *money.Money, userId string) (*Account, error) {
+	if limit != nil && limit.Units < 0 {
+		return nil, fmt.Errorf("%w: overdraft limit cannot be negative", money.ErrNegativeAmount)
+	}
+
+	account := &Account{Id: accountId}
+	err := runInTx(ctx, a.db, &sql.TxOptions{Isolation: sql.LevelReadCommitted}, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "SELECT account_state, account_type, currency FROM accounts WHERE id = $1 FOR UPDATE", accountId).
+			Scan(&account.AccountState, &account.AccountType, &account.Currency)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrAccountNotFound, accountId)
+		}
+		if err != nil {
+			return err
+		}
+
+		account.UnlimitedOverdraft = limit == nil
+		if limit != nil {
+			if limit.Currency != account.Currency {
+				return fmt.Errorf("%w: limit is in %s but account %s is in %s", money.ErrCurrencyMismatch, limit.Currency, accountId, account.Currency)
+			}
+			account.OverdraftLimit = limit.Units
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE accounts SET overdraft_limit = $2, updated_by = $3 WHERE id = $1",
+			accountId, overdraftLimit(account.OverdraftLimit, account.UnlimitedOverdraft), userId)
+		return err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error while setting overdraft limit", "error", err)
+		return nil, err
+	}
+	return account, nil
+}
+
+// overdraftLimit returns an overdraft limit as stored in accounts, where NULL is unlimited
+func overdraftLimit(limit int64, unlimited bool) sql.NullInt64 {
+	return sql.NullInt64{Int64: limit, Valid: !unlimited}
+}
+
+// GetAccountBalance returns the balance of an account from every ledger entry posted, and
+// every hold that was pending, at asOf. A zero asOf returns the current balance from the
+// account_balances projection.
+//
+// Overdraft limits are not versioned, so a balance at a past asOf comes with the account's
+// current overdraft limit rather than the one it had then.
+func (a *AccountRepository) GetAccountBalance(ctx context.Context, accountId string, asOf time.Time) (*Balance, error) {
+	if asOf.IsZero() {
+		return a.getCurrentBalance(ctx, accountId)
+	}
+
+	var cur string
+	var limit sql.NullInt64
+	balance := &Balance{AccountId: accountId, AsOf: asOf.UTC()}
+	err := a.db.QueryRowContext(ctx, `
+		SELECT
+			a.currency,
+			a.overdraft_limit,
+			COALESCE((
+				SELECT SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END)
+				FROM ledger_entries
+				WHERE account_id = $1 AND created_at <= $2
+			), 0) AS posted,
+			COALESCE((
+				SELECT SUM(amount)
+				FROM holds
+				WHERE debit_account_id = $1 AND created_at <= $2 AND (resolved_at IS NULL OR resolved_at > $2)
+			), 0) AS pending
+		FROM accounts a
+		WHERE a.id = $1
+	`, accountId, balance.AsOf).Scan(&cur, &limit, &balance.Amount.Units, &balance.Pending.Units)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountId)
+	}
 	if err != nil {
 		slog.ErrorContext(ctx, "error while getting account balance", "error", err)
-		return 0, err
+		return nil, err
 	}
-	fmt.Println("balance", balance)
+	balance.Available.Units = balance.Amount.Units - balance.Pending.Units
+	balance.setOverdraft(limit)
+	balance.setCurrency(cur)
 	return balance, nil
 }
+
+// getCurrentBalance reads the balance of an account from its projection
+func (a *AccountRepository) getCurrentBalance(ctx context.Context, accountId string) (*Balance, error) {
+	var cur string
+	var limit sql.NullInt64
+	balance := &Balance{AccountId: accountId}
+	err := a.db.QueryRowContext(ctx, `
+		SELECT b.currency, b.posted, b.pending, b.available, a.overdraft_limit, CURRENT_TIMESTAMP
+		FROM account_balances b
+		JOIN accounts a ON a.id = b.account_id
+		WHERE b.account_id = $1
+	`, accountId).Scan(&cur, &balance.Amount.Units, &balance.Pending.Units, &balance.Available.Units, &limit, &balance.AsOf)
+	// Every account gets a projection row when it is created, so no row means no account
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountId)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error while getting account balance", "error", err)
+		return nil, err
+	}
+	balance.AsOf = balance.AsOf.UTC()
+	balance.setOverdraft(limit)
+	balance.setCurrency(cur)
+	return balance, nil
+}
+
+func (b *Balance) setCurrency(code string) {
+	b.Amount.Currency = code
+	b.Pending.Currency = code
+	b.Available.Currency = code
+	b.OverdraftLimit.Currency = code
+}
+
+func (b *Balance) setOverdraft(limit sql.NullInt64) {
+	b.OverdraftLimit.Units = limit.Int64
+	b.UnlimitedOverdraft = !limit.Valid
+}
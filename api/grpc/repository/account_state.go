@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+)
+
+const (
+	AccountStateOpen   = "open"
+	AccountStateFrozen = "frozen"
+	AccountStateClosed = "closed"
+)
+
+var (
+	// ErrInvalidAccountState is returned for an account state other than open, frozen or closed
+	ErrInvalidAccountState = errors.New("invalid account state")
+	// ErrAccountFrozen is returned when a posting would debit a frozen account
+	ErrAccountFrozen = errors.New("account is frozen")
+	// ErrAccountClosed is returned when a posting touches a closed account
+	ErrAccountClosed = errors.New("account is closed")
+	// ErrInvalidStateTransition is returned when an account cannot move to the requested state
+	ErrInvalidStateTransition = fmt.Errorf("%w transition", ErrInvalidAccountState)
+)
+
+// accountStatePolicy lists the directions in which an account in each state can be posted.
+// A frozen account can still receive funds but cannot send them; a closed account can do
+// neither.
+var accountStatePolicy = map[string]map[string]bool{
+	AccountStateOpen:   {"debit": true, "credit": true},
+	AccountStateFrozen: {"credit": true},
+	AccountStateClosed: {},
+}
+
+// accountStateTransitions lists the states an account in each state can move to. Closing
+// an account is permanent.
+var accountStateTransitions = map[string]map[string]bool{
+	AccountStateOpen:   {AccountStateFrozen: true, AccountStateClosed: true},
+	AccountStateFrozen: {AccountStateOpen: true, AccountStateClosed: true},
+	AccountStateClosed: {},
+}
+
+// validAccountState returns the state an account is created in, defaulting to open
+func validAccountState(state string) (string, error) {
+	if state == "" {
+		return AccountStateOpen, nil
+	}
+	if _, ok := accountStatePolicy[state]; !ok {
+		return "", fmt.Errorf("%w %q", ErrInvalidAccountState, state)
+	}
+	return state, nil
+}
+
+// postingAccount is what a posting needs to know about each account it touches
+type postingAccount struct {
+	id       string
+	currency string
+	state    string
+}
+
+// readPostingAccount reads an account's currency and state. The account row is share
+// locked until the end of the transaction so that its state cannot change while the
+// posting is in flight.
+func readPostingAccount(ctx context.Context, tx *sql.Tx, accountId string) (*postingAccount, error) {
+	a := &postingAccount{id: accountId}
+	err := tx.QueryRowContext(ctx, "SELECT currency, account_state FROM accounts WHERE id = $1 FOR SHARE", accountId).Scan(&a.currency, &a.state)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// allows returns an error if the account's state does not allow it to be posted in direction
+func (a *postingAccount) allows(direction string) error {
+	if accountStatePolicy[a.state][direction] {
+		return nil
+	}
+	switch a.state {
+	case AccountStateFrozen:
+		return fmt.Errorf("%w: account %s cannot be %sed", ErrAccountFrozen, a.id, direction)
+	case AccountStateClosed:
+		return fmt.Errorf("%w: account %s cannot be %sed", ErrAccountClosed, a.id, direction)
+	}
+	return fmt.Errorf("%w %q: account %s cannot be %sed", ErrInvalidAccountState, a.state, a.id, direction)
+}
+
+// checkLegStates returns an error if the state of any account in legs does not allow the
+// leg's direction
+func checkLegStates(ctx context.Context, tx *sql.Tx, legs []ledgerLeg) error {
+	for _, leg := range legs {
+		account, err := readPostingAccount(ctx, tx, leg.accountId)
+		if err != nil {
+			return err
+		}
+		if err := account.allows(leg.direction); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FreezeAccount stops funds from leaving an account. It can still receive funds.
+func (a *AccountRepository) FreezeAccount(ctx context.Context, accountId, userId string) (*Account, error) {
+	return a.transitionAccountState(ctx, accountId, AccountStateFrozen, userId)
+}
+
+// UnfreezeAccount reopens a frozen account
+func (a *AccountRepository) UnfreezeAccount(ctx context.Context, accountId, userId string) (*Account, error) {
+	return a.transitionAccountState(ctx, accountId, AccountStateOpen, userId)
+}
+
+// CloseAccount permanently closes an account. The account must have a zero balance and no
+// pending holds.
+func (a *AccountRepository) CloseAccount(ctx context.Context, accountId, userId string) (*Account, error) {
+	return a.transitionAccountState(ctx, accountId, AccountStateClosed, userId)
+}
+
+// transitionAccountState moves an account to a new state. The previous state is recorded
+// in the account's history by the accounts history trigger.
+func (a *AccountRepository) transitionAccountState(ctx context.Context, accountId, to, userId string) (*Account, error) {
+	account := &Account{Id: accountId}
+	err := runInTx(ctx, a.db, &sql.TxOptions{Isolation: sql.LevelReadCommitted}, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		// The exclusive lock waits for in-flight postings, which hold a share lock on the account
+		err := tx.QueryRowContext(ctx, "SELECT account_state, account_type, currency FROM accounts WHERE id = $1 FOR UPDATE", accountId).
+			Scan(&account.AccountState, &account.AccountType, &account.Currency)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("%w: %s", ErrAccountNotFound, accountId)
+		}
+		if err != nil {
+			return err
+		}
+		if !accountStateTransitions[account.AccountState][to] {
+			return fmt.Errorf("%w: account %s cannot move from %s to %s", ErrInvalidStateTransition, accountId, account.AccountState, to)
+		}
+
+		if to == AccountStateClosed {
+			balance, err := readAccountBalance(ctx, tx, accountId)
+			if err != nil {
+				return err
+			}
+			if balance.posted != 0 || balance.pending != 0 {
+				return fmt.Errorf("%w: account %s has a balance of %s and %s pending", ErrAccountNotEmpty, accountId,
+					money.Money{Units: balance.posted, Currency: account.Currency}, money.Money{Units: balance.pending, Currency: account.Currency})
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE accounts SET account_state = $2, updated_by = $3 WHERE id = $1", accountId, to, userId)
+		if err != nil {
+			slog.ErrorContext(ctx, "error while updating account state", "error", err)
+			return err
+		}
+		err = recordEvent(ctx, tx, accountStateEvents[to], accountId, accountStateEvent{
+			AccountId:     accountId,
+			AccountState:  to,
+			PreviousState: account.AccountState,
+			ChangedBy:     userId,
+		})
+		if err != nil {
+			return err
+		}
+		account.AccountState = to
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
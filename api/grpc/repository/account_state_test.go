@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"testing"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestAccountPostingPolicy(t *testing.T) {
+	tests := []struct {
+		state     string
+		direction string
+		wantErr   error
+	}{
+		{state: AccountStateOpen, direction: "debit"},
+		{state: AccountStateOpen, direction: "credit"},
+		{state: AccountStateFrozen, direction: "debit", wantErr: ErrAccountFrozen},
+		{state: AccountStateFrozen, direction: "credit"},
+		{state: AccountStateClosed, direction: "debit", wantErr: ErrAccountClosed},
+		{state: AccountStateClosed, direction: "credit", wantErr: ErrAccountClosed},
+		{state: "active", direction: "credit", wantErr: ErrInvalidAccountState},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state+" "+tt.direction, func(t *testing.T) {
+			account := &postingAccount{id: "acct_1", currency: "USD", state: tt.state}
+			err := account.allows(tt.direction)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAccountRepository_StateTransitions(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_withdraw_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	accounts := NewAccountRepository(db, "acct_")
+	transactions := NewTransactionRepository(db, "txn_", "le_")
+
+	// acct_1 holds 150 and acct_3 is empty
+	t.Run("frozen account can receive but not send", func(t *testing.T) {
+		account, err := accounts.FreezeAccount(ctx, "acct_1", "usr_1")
+		assert.NoError(t, err)
+		assert.Equal(t, AccountStateFrozen, account.AccountState)
+
+		_, err = transactions.WithdrawFunds(ctx, usd(10), "usr_1", "acct_1", "acct_2", "")
+		assert.ErrorIs(t, err, ErrAccountFrozen)
+		_, err = transactions.DepositFunds(ctx, usd(10), "usr_1", "acct_2", "acct_1", "")
+		assert.NoError(t, err)
+
+		_, err = accounts.FreezeAccount(ctx, "acct_1", "usr_1")
+		assert.ErrorIs(t, err, ErrInvalidAccountState)
+	})
+
+	t.Run("unfrozen account can send again", func(t *testing.T) {
+		account, err := accounts.UnfreezeAccount(ctx, "acct_1", "usr_1")
+		assert.NoError(t, err)
+		assert.Equal(t, AccountStateOpen, account.AccountState)
+
+		_, err = transactions.WithdrawFunds(ctx, usd(10), "usr_1", "acct_1", "acct_2", "")
+		assert.NoError(t, err)
+	})
+
+	t.Run("state changes are recorded in history", func(t *testing.T) {
+		var states []string
+		rows, err := db.Query("SELECT h->>'account_state' FROM accounts, unnest(history) AS h WHERE id = 'acct_1'")
+		assert.NoError(t, err)
+		defer rows.Close()
+		for rows.Next() {
+			var state string
+			assert.NoError(t, rows.Scan(&state))
+			states = append(states, state)
+		}
+		assert.Equal(t, []string{AccountStateOpen, AccountStateFrozen}, states)
+	})
+
+	t.Run("account with a balance cannot be closed", func(t *testing.T) {
+		_, err := accounts.CloseAccount(ctx, "acct_1", "usr_1")
+		assert.Error(t, err)
+	})
+
+	t.Run("closed account cannot be posted to or reopened", func(t *testing.T) {
+		account, err := accounts.CloseAccount(ctx, "acct_3", "usr_2")
+		assert.NoError(t, err)
+		assert.Equal(t, AccountStateClosed, account.AccountState)
+
+		_, err = transactions.DepositFunds(ctx, usd(10), "usr_2", "acct_7", "acct_3", "")
+		assert.ErrorIs(t, err, ErrAccountClosed)
+		_, err = accounts.UnfreezeAccount(ctx, "acct_3", "usr_2")
+		assert.ErrorIs(t, err, ErrInvalidAccountState)
+	})
+
+	t.Run("account does not exist", func(t *testing.T) {
+		_, err := accounts.FreezeAccount(ctx, "acct_6", "usr_1")
+		assert.Error(t, err)
+	})
+}
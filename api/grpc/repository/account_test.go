@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
 	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
 	"github.com/testcontainers/testcontainers-go"
 	"log"
@@ -27,10 +30,34 @@ func TestCreateAccount(t *testing.T) {
 	}{
 		{
 			name: "successful insert",
+			input: &Account{
+				AccountState: "open",
+				AccountType:  "savings",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "state defaults to open",
+			input: &Account{
+				AccountType: "savings",
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "unknown state",
 			input: &Account{
 				AccountState: "active",
 				AccountType:  "savings",
 			},
+			expectedErr: ErrInvalidAccountState,
+		},
+		{
+			name: "successful insert in euros",
+			input: &Account{
+				AccountState: "open",
+				AccountType:  "debit",
+				Currency:     "EUR",
+			},
 			expectedErr: nil,
 		},
 	}
@@ -39,7 +66,7 @@ func TestCreateAccount(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			_, err := repo.CreateAccount(context.Background(), tt.input)
-			if err != tt.expectedErr {
+			if !errors.Is(err, tt.expectedErr) {
 				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
 			}
 		})
@@ -59,8 +86,10 @@ func TestGetAccountBalance(t *testing.T) {
 	tests := []struct {
 		name        string
 		accountId   string
+		asOf        time.Time
 		expectedBal int64
 		expectedErr bool
+		errIs       error
 	}{
 		{
 			name:        "Valid internal balance",
@@ -76,23 +105,195 @@ func TestGetAccountBalance(t *testing.T) {
 		},
 		{
 			name:        "No balance",
-			accountId:   "account_3",
+			accountId:   "acct_3",
 			expectedBal: 0,
 			expectedErr: false,
 		},
+		{
+			name:        "Unknown account",
+			accountId:   "acct_missing",
+			expectedErr: true,
+			errIs:       ErrAccountNotFound,
+		},
+		{
+			name:        "Internal balance at end of January",
+			accountId:   "acct_1",
+			asOf:        time.Date(2024, time.January, 31, 23, 59, 59, 0, time.UTC),
+			expectedBal: 500,
+			expectedErr: false,
+		},
+		{
+			name:        "Balance at the exact time of a posting includes it",
+			accountId:   "acct_1",
+			asOf:        time.Date(2024, time.February, 10, 9, 30, 0, 0, time.UTC),
+			expectedBal: 450,
+			expectedErr: false,
+		},
+		{
+			name:        "Balance before the first posting",
+			accountId:   "acct_2",
+			asOf:        time.Date(2023, time.December, 31, 23, 59, 59, 0, time.UTC),
+			expectedBal: 0,
+			expectedErr: false,
+		},
+		{
+			name:        "Unknown account at a past time",
+			accountId:   "acct_missing",
+			asOf:        time.Date(2024, time.January, 31, 23, 59, 59, 0, time.UTC),
+			expectedErr: true,
+			errIs:       ErrAccountNotFound,
+		},
 	}
 
 	repo := &AccountRepository{db: db}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			balance, err := repo.GetAccountBalance(context.Background(), tt.accountId)
+			balance, err := repo.GetAccountBalance(context.Background(), tt.accountId, tt.asOf)
 
 			if tt.expectedErr {
 				assert.Error(t, err)
+				if tt.errIs != nil {
+					assert.ErrorIs(t, err, tt.errIs)
+				}
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, tt.expectedBal, balance)
+				assert.Equal(t, tt.expectedBal, balance.Amount.Units)
+				if !tt.asOf.IsZero() {
+					assert.True(t, tt.asOf.Equal(balance.AsOf))
+				}
 			}
 		})
 	}
 }
+
+func TestSetOverdraftLimit(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_withdraw_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	accounts := NewAccountRepository(db, "acct_")
+	transactions := NewTransactionRepository(db, "txn_", "le_")
+
+	// acct_3 is empty
+	_, err := transactions.WithdrawFunds(ctx, usd(50), "usr_2", "acct_3", "acct_7", "")
+	assert.Error(t, err)
+
+	limit := func(m money.Money) *money.Money { return &m }
+	_, err = accounts.SetOverdraftLimit(ctx, "acct_3", limit(usd(-1)), "usr_2")
+	assert.Error(t, err)
+	_, err = accounts.SetOverdraftLimit(ctx, "acct_3", limit(money.Money{Units: 100, Currency: "EUR"}), "usr_2")
+	assert.Error(t, err)
+	_, err = accounts.SetOverdraftLimit(ctx, "acct_6", limit(usd(100)), "usr_2")
+	assert.Error(t, err)
+
+	account, err := accounts.SetOverdraftLimit(ctx, "acct_3", limit(usd(100)), "usr_2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), account.OverdraftLimit)
+	assert.False(t, account.UnlimitedOverdraft)
+
+	_, err = transactions.WithdrawFunds(ctx, usd(50), "usr_2", "acct_3", "acct_7", "")
+	assert.NoError(t, err)
+	_, err = transactions.WithdrawFunds(ctx, usd(51), "usr_2", "acct_3", "acct_7", "")
+	assert.Error(t, err)
+
+	balance, err := accounts.GetAccountBalance(ctx, "acct_3", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-50), balance.Available.Units)
+	assert.Equal(t, int64(100), balance.OverdraftLimit.Units)
+	assert.False(t, balance.UnlimitedOverdraft)
+
+	account, err = accounts.SetOverdraftLimit(ctx, "acct_3", nil, "usr_2")
+	assert.NoError(t, err)
+	assert.True(t, account.UnlimitedOverdraft)
+	_, err = transactions.WithdrawFunds(ctx, usd(100000), "usr_2", "acct_3", "acct_7", "")
+	assert.NoError(t, err)
+}
+
+func TestGetAndListAccounts(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_withdraw_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	accounts := NewAccountRepository(db, "acct_")
+
+	t.Run("get account with its balance", func(t *testing.T) {
+		account, err := accounts.GetAccount(ctx, "acct_1")
+		assert.NoError(t, err)
+		assert.Equal(t, "usr_1", account.UserId)
+		assert.Equal(t, "debit", account.AccountType)
+		assert.Equal(t, AccountStateOpen, account.AccountState)
+		assert.False(t, account.CreatedAt.IsZero())
+		assert.Equal(t, usd(150), account.Balance.Amount)
+		assert.Equal(t, usd(150), account.Balance.Available)
+
+		_, err = accounts.GetAccount(ctx, "acct_6")
+		assert.ErrorIs(t, err, ErrAccountNotFound)
+	})
+
+	ids := func(list []Account) []string {
+		var ids []string
+		for _, a := range list {
+			ids = append(ids, a.Id)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name   string
+		filter AccountFilter
+		want   []string
+	}{
+		{name: "by user", filter: AccountFilter{UserId: "usr_1"}, want: []string{"acct_1", "acct_2"}},
+		{name: "by type", filter: AccountFilter{AccountType: "credit"}, want: []string{"acct_2", "acct_5", "acct_7"}},
+		{name: "by user and type", filter: AccountFilter{UserId: "usr_2", AccountType: "debit"}, want: []string{"acct_3"}},
+		{name: "by state", filter: AccountFilter{AccountState: AccountStateFrozen}, want: nil},
+		{name: "after cursor", filter: AccountFilter{Cursor: "acct_3"}, want: []string{"acct_5", "acct_7"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, next, err := accounts.ListAccounts(ctx, tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ids(list))
+			assert.Empty(t, next)
+		})
+	}
+
+	t.Run("page by page", func(t *testing.T) {
+		var pages [][]string
+		filter := AccountFilter{Limit: 2}
+		for {
+			list, next, err := accounts.ListAccounts(ctx, filter)
+			assert.NoError(t, err)
+			pages = append(pages, ids(list))
+			if next == "" {
+				break
+			}
+			filter.Cursor = next
+		}
+		assert.Equal(t, [][]string{{"acct_1", "acct_2"}, {"acct_3", "acct_5"}, {"acct_7"}}, pages)
+	})
+
+	t.Run("create account for a user", func(t *testing.T) {
+		_, err := accounts.CreateAccount(ctx, &Account{UserId: "usr_9", AccountType: "debit"})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+
+		id, err := accounts.CreateAccount(ctx, &Account{UserId: "usr_3", AccountType: "debit"})
+		assert.NoError(t, err)
+		account, err := accounts.GetAccount(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, "usr_3", account.UserId)
+		assert.Equal(t, usd(0), account.Balance.Amount)
+	})
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// ErrConcurrentUpdate is returned when an account's balance changed between being read and
+// being written by a posting. The posting has been rolled back and is safe to retry.
+var ErrConcurrentUpdate = errors.New("account balance was modified concurrently")
+
+// accountBalance is a row of the account_balances projection
+type accountBalance struct {
+	accountId string
+	currency  string
+	posted    int64
+	pending   int64
+	available int64
+	version   int64
+	// overdraftLimit is the account's overdraft limit, NULL when it is unlimited
+	overdraftLimit sql.NullInt64
+}
+
+// balanceChange is a change to be applied to an account's projected balance
+type balanceChange struct {
+	balance      *accountBalance
+	postedDelta  int64
+	pendingDelta int64
+}
+
+// BalanceDrift is an account whose projected balance did not match the sum of its ledger
+// entries and pending holds
+type BalanceDrift struct {
+	AccountId        string
+	ProjectedPosted  int64
+	LedgerPosted     int64
+	ProjectedPending int64
+	HeldPending      int64
+}
+
+// readAccountBalance reads the projected balance of an account along with its version and
+// overdraft limit
+func readAccountBalance(ctx context.Context, tx *sql.Tx, accountId string) (*accountBalance, error) {
+	b := &accountBalance{accountId: accountId}
+	err := tx.QueryRowContext(ctx, `
+		SELECT b.currency, b.posted, b.pending, b.available, b.version, a.overdraft_limit
+		FROM account_balances b
+		JOIN accounts a ON a.id = b.account_id
+		WHERE b.account_id = $1
+	`, accountId).Scan(&b.currency, &b.posted, &b.pending, &b.available, &b.version, &b.overdraftLimit)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// updateAccountBalance applies a change to the posted and pending balances of an account,
+// provided the projection has not been modified since it was read, and records a
+// balance.changed event. Available balance is always posted minus pending.
+func updateAccountBalance(ctx context.Context, tx *sql.Tx, b *accountBalance, postedDelta, pendingDelta int64) error {
+	res, err := tx.ExecContext(ctx, `
+		UPDATE account_balances
+		SET posted = posted + $2, pending = pending + $3, available = available + $2 - $3, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE account_id = $1 AND version = $4
+	`, b.accountId, postedDelta, pendingDelta, b.version)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		slog.WarnContext(ctx, "account balance version conflict", "account_id", b.accountId, "version", b.version)
+		return fmt.Errorf("%w: account %s", ErrConcurrentUpdate, b.accountId)
+	}
+	b.posted += postedDelta
+	b.pending += pendingDelta
+	b.available += postedDelta - pendingDelta
+	b.version++
+	return recordEvent(ctx, tx, EventBalanceChanged, b.accountId, balanceChangedEvent{
+		AccountId: b.accountId,
+		Currency:  b.currency,
+		Posted:    b.posted,
+		Pending:   b.pending,
+		Available: b.available,
+		Version:   b.version,
+	})
+}
+
+// applyBalanceChanges applies changes to several accounts' projected balances. Rows are
+// updated in account id order so that concurrent postings cannot deadlock each other.
+func applyBalanceChanges(ctx context.Context, tx *sql.Tx, changes ...balanceChange) error {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].balance.accountId < changes[j].balance.accountId
+	})
+	for _, c := range changes {
+		if err := updateAccountBalance(ctx, tx, c.balance, c.postedDelta, c.pendingDelta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RebuildBalances recomputes every account's projected balance from its ledger entries and
+// pending holds, and returns the accounts whose projection had drifted. With dryRun the
+// drift is only reported.
+func (a *AccountRepository) RebuildBalances(ctx context.Context, dryRun bool) ([]BalanceDrift, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT balance_account_id, projected_posted, ledger_posted, projected_pending, held_pending
+		FROM rebuild_account_balances($1)
+	`, dryRun)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while rebuilding account balances", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drift []BalanceDrift
+	for rows.Next() {
+		var d BalanceDrift
+		if err := rows.Scan(&d.AccountId, &d.ProjectedPosted, &d.LedgerPosted, &d.ProjectedPending, &d.HeldPending); err != nil {
+			return nil, fmt.Errorf("error scanning balance drift: %w", err)
+		}
+		drift = append(drift, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating balance drift: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing balance rebuild: %w", err)
+	}
+	return drift, nil
+}
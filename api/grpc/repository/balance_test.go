@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestRebuildBalances(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_accounts_get_balance.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	repo := NewAccountRepository(db, "acct_")
+	ctx := context.Background()
+
+	// The seed rebuilds the projection, so nothing has drifted yet
+	drift, err := repo.RebuildBalances(ctx, true)
+	assert.NoError(t, err)
+	assert.Empty(t, drift)
+
+	// Corrupt the projection behind the ledger's back
+	_, err = db.Exec("UPDATE account_balances SET posted = 0, available = 0 WHERE account_id = 'acct_1'")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		dryRun        bool
+		expectedDrift []BalanceDrift
+	}{
+		{
+			name:          "dry run reports drift",
+			dryRun:        true,
+			expectedDrift: []BalanceDrift{{AccountId: "acct_1", ProjectedPosted: 0, LedgerPosted: 450}},
+		},
+		{
+			name:          "dry run does not correct drift",
+			dryRun:        true,
+			expectedDrift: []BalanceDrift{{AccountId: "acct_1", ProjectedPosted: 0, LedgerPosted: 450}},
+		},
+		{
+			name:          "rebuild corrects drift",
+			dryRun:        false,
+			expectedDrift: []BalanceDrift{{AccountId: "acct_1", ProjectedPosted: 0, LedgerPosted: 450}},
+		},
+		{
+			name:          "no drift after rebuild",
+			dryRun:        true,
+			expectedDrift: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drift, err := repo.RebuildBalances(ctx, tt.dryRun)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDrift, drift)
+		})
+	}
+
+	balance, err := repo.GetAccountBalance(ctx, "acct_1", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(450), balance.Amount.Units)
+}
+
+func TestUpdateAccountBalance_VersionConflict(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_accounts_get_balance.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	assert.NoError(t, err)
+	defer tx.Rollback()
+
+	b, err := readAccountBalance(ctx, tx, "acct_1")
+	assert.NoError(t, err)
+
+	// A concurrent posting commits after the balance was read
+	_, err = db.Exec("UPDATE account_balances SET version = version + 1 WHERE account_id = 'acct_1'")
+	assert.NoError(t, err)
+
+	err = updateAccountBalance(ctx, tx, b, -100, 0)
+	assert.True(t, errors.Is(err, ErrConcurrentUpdate), "expected ErrConcurrentUpdate, got %v", err)
+}
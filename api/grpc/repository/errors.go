@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// Domain errors returned by the repositories. They are wrapped with the details of the
+// failure, so compare them with errors.Is.
+var (
+	ErrAccountNotFound     = errors.New("account not found")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrHoldNotFound        = errors.New("hold not found")
+	ErrUserNotFound        = errors.New("user not found")
+
+	ErrWebhookEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrPaymentMethodNotFound   = errors.New("payment method not found")
+
+	// ErrInsufficientFunds is returned when a debit would take an account below its overdraft limit
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrDuplicateEmail is returned when a user is created with an email that is already in use
+	ErrDuplicateEmail = errors.New("email is already in use")
+	// ErrInvalidRequest is returned for a request that can never succeed as given
+	ErrInvalidRequest = errors.New("invalid request")
+
+	// ErrHoldNotPending is returned when a hold that was already resolved, or has expired,
+	// is captured or voided
+	ErrHoldNotPending = errors.New("hold is not pending")
+	// ErrNotReversible is returned when a transaction cannot be reversed (any further)
+	ErrNotReversible = errors.New("transaction cannot be reversed")
+	// ErrUserDeactivated is returned when a deactivated user moves money or is changed
+	ErrUserDeactivated = errors.New("user is deactivated")
+	// ErrAccountNotOwned is returned when a user moves money out of or into an account
+	// that is not linked to them
+	ErrAccountNotOwned = errors.New("account does not belong to user")
+	// ErrInvalidPosting is returned when a deposit, withdrawal or transfer goes between the
+	// wrong kinds of accounts, e.g. a deposit that credits an external account
+	ErrInvalidPosting = errors.New("invalid posting")
+	// ErrAccountNotEmpty is returned when an account with a balance or pending holds is closed
+	ErrAccountNotEmpty = errors.New("account is not empty")
+	// ErrWebhookDeliveryPending is returned when a delivery that is still being attempted
+	// is redelivered
+	ErrWebhookDeliveryPending = errors.New("webhook delivery is still pending")
+	// ErrWebhookEndpointNotOwned is returned when a user deletes, lists the deliveries of or
+	// redelivers to an endpoint that belongs to someone else
+	ErrWebhookEndpointNotOwned = errors.New("webhook endpoint does not belong to user")
+	// ErrPaymentMethodNotOwned is returned when a user uses, verifies or removes a payment
+	// method that belongs to someone else
+	ErrPaymentMethodNotOwned = errors.New("payment method does not belong to user")
+	// ErrPaymentMethodNotVerified is returned when money is moved with a payment method that
+	// has not been verified
+	ErrPaymentMethodNotVerified = errors.New("payment method is not verified")
+)
+
+// uniqueViolation is the Postgres error code for a unique constraint violation
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether err is a violation of the named unique constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == constraint
+}
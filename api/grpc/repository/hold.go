@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/currency"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+)
+
+const (
+	HoldStatusPending = "pending"
+	HoldStatusPosted  = "posted"
+	HoldStatusVoided  = "voided"
+	HoldStatusExpired = "expired"
+)
+
+// DefaultHoldTTL is how long a hold reserves funds when it is created without an expiry
+const DefaultHoldTTL = 7 * 24 * time.Hour
+
+// expireHoldsBatchSize bounds how many holds a single ExpireHolds call resolves
+const expireHoldsBatchSize = 100
+
+// Hold is a reservation of funds on the debited account that is later captured to the
+// credited account, voided, or expires
+type Hold struct {
+	Id              string
+	DebitAccountId  string
+	CreditAccountId string
+	Amount          money.Money
+	CapturedAmount  money.Money
+	Status          string
+	ExpiresAt       time.Time
+	TransactionId   string
+	CreatedAt       time.Time
+}
+
+type HoldRepository struct {
+	db      *sql.DB
+	txnRepo *TransactionRepository
+	ID      identifier.ID
+}
+
+func NewHoldRepository(db *sql.DB, txnRepo *TransactionRepository, prefix string) *HoldRepository {
+	return &HoldRepository{db: db, txnRepo: txnRepo, ID: identifier.ID(prefix)}
+}
+
+// CreateHold reserves amount on the debited account. The held amount reduces the account's
+// available balance but not its posted balance until the hold is captured. A captured hold
+// moves money like a transfer, so it must be between the same accounts a transfer may be.
+func (h *HoldRepository) CreateHold(ctx context.Context, amount money.Money, userId, debitAccountId, creditAccountId string, expiresAt time.Time) (*Hold, error) {
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(DefaultHoldTTL)
+	}
+	if !expiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: hold expiry %s is in the past", ErrInvalidRequest, expiresAt.Format(time.RFC3339))
+	}
+
+	var hold *Hold
+	err := h.txnRepo.runInTx(ctx, func(tx *sql.Tx) error {
+		if err := checkLinkedAccounts(ctx, tx, postingTransfer, userId, debitAccountId, creditAccountId); err != nil {
+			return err
+		}
+		if _, err := h.txnRepo.postingCurrency(ctx, tx, amount, debitAccountId, creditAccountId); err != nil {
+			slog.ErrorContext(ctx, "error resolving hold currency", "error", err)
+			return err
+		}
+
+		debitBalance, err := readAccountBalance(ctx, tx, debitAccountId)
+		if err != nil {
+			return err
+		}
+		if !h.txnRepo.checkSufficientBalance(debitBalance, amount.Units) {
+			slog.Error("insufficient balance", "account_id", debitAccountId)
+			return fmt.Errorf("%w in account %s", ErrInsufficientFunds, debitAccountId)
+		}
+
+		hold = &Hold{
+			Id:              string(h.ID.New()),
+			DebitAccountId:  debitAccountId,
+			CreditAccountId: creditAccountId,
+			Amount:          amount,
+			CapturedAmount:  money.Money{Currency: amount.Currency},
+			Status:          HoldStatusPending,
+			ExpiresAt:       expiresAt.UTC(),
+		}
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO holds (id, debit_account_id, credit_account_id, amount, currency, status, expires_at, created_by, updated_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+			RETURNING created_at
+		`, hold.Id, debitAccountId, creditAccountId, amount.Units, amount.Currency, hold.Status, hold.ExpiresAt, userId).Scan(&hold.CreatedAt)
+		if err != nil {
+			slog.ErrorContext(ctx, "error while creating hold", "error", err)
+			return err
+		}
+
+		return applyBalanceChanges(ctx, tx, balanceChange{balance: debitBalance, pendingDelta: amount.Units})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// CaptureHold posts a pending hold to the ledger. A nil amount captures the full hold, a
+// smaller amount is a partial capture and the remainder is released. A hold can only be
+// captured once.
+func (h *HoldRepository) CaptureHold(ctx context.Context, holdId string, amount *money.Money, userId string) (*Hold, error) {
+	var hold *Hold
+	err := h.txnRepo.runInTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		hold, err = h.lockPendingHold(ctx, tx, holdId)
+		if err != nil {
+			return err
+		}
+		if !hold.ExpiresAt.After(time.Now()) {
+			return fmt.Errorf("%w: hold %s expired at %s", ErrHoldNotPending, holdId, hold.ExpiresAt.Format(time.RFC3339))
+		}
+
+		captured := hold.Amount
+		if amount != nil {
+			if amount.Currency != hold.Amount.Currency {
+				return fmt.Errorf("%w: capture is in %s but hold is in %s", money.ErrCurrencyMismatch, amount.Currency, hold.Amount.Currency)
+			}
+			if amount.Units <= 0 || amount.Units > hold.Amount.Units {
+				return fmt.Errorf("%w: capture amount %s must be greater than zero and at most the held %s", money.ErrInvalidAmount, amount, hold.Amount)
+			}
+			captured = *amount
+		}
+
+		cur, err := currency.Lookup(hold.Amount.Currency)
+		if err != nil {
+			return err
+		}
+		// Either account may have been frozen or closed since the hold was placed
+		err = checkLegStates(ctx, tx, []ledgerLeg{
+			{accountId: hold.DebitAccountId, direction: "debit"},
+			{accountId: hold.CreditAccountId, direction: "credit"},
+		})
+		if err != nil {
+			return err
+		}
+		debitBalance, err := readAccountBalance(ctx, tx, hold.DebitAccountId)
+		if err != nil {
+			return err
+		}
+
+		txnId, err := h.txnRepo.insertDoubleEntry(ctx, tx, cur, captured.Units, hold.DebitAccountId, hold.CreditAccountId, userId, "")
+		if err != nil {
+			return err
+		}
+
+		// Release the whole reservation and post the captured amount in one balance update
+		changes := []balanceChange{{balance: debitBalance, postedDelta: -captured.Units, pendingDelta: -hold.Amount.Units}}
+		if hold.CreditAccountId != hold.DebitAccountId {
+			creditBalance, err := readAccountBalance(ctx, tx, hold.CreditAccountId)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, balanceChange{balance: creditBalance, postedDelta: captured.Units})
+		} else {
+			changes[0].postedDelta = 0
+		}
+		if err := applyBalanceChanges(ctx, tx, changes...); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE holds
+			SET status = $2, captured_amount = $3, transaction_id = $4, resolved_at = CURRENT_TIMESTAMP, updated_by = $5
+			WHERE id = $1
+		`, holdId, HoldStatusPosted, captured.Units, txnId, userId)
+		if err != nil {
+			slog.ErrorContext(ctx, "error while capturing hold", "error", err)
+			return err
+		}
+
+		hold.Status = HoldStatusPosted
+		hold.CapturedAmount = captured
+		hold.TransactionId = txnId
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// VoidHold releases a pending hold without posting anything to the ledger
+func (h *HoldRepository) VoidHold(ctx context.Context, holdId, userId string) (*Hold, error) {
+	var hold *Hold
+	err := h.txnRepo.runInTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		hold, err = h.lockPendingHold(ctx, tx, holdId)
+		if err != nil {
+			return err
+		}
+		return h.releaseHold(ctx, tx, hold, HoldStatusVoided, userId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// ExpireHolds releases pending holds whose expiry has passed and returns how many were
+// expired. Each hold is expired in its own transaction so one conflict does not hold up the rest.
+func (h *HoldRepository) ExpireHolds(ctx context.Context) (int, error) {
+	rows, err := h.db.QueryContext(ctx, `
+		SELECT id FROM holds
+		WHERE status = $1 AND expires_at <= CURRENT_TIMESTAMP
+		ORDER BY expires_at
+		LIMIT $2
+	`, HoldStatusPending, expireHoldsBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("error querying expired holds: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error scanning expired hold: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating expired holds: %w", err)
+	}
+
+	expired := 0
+	for _, id := range ids {
+		err := h.txnRepo.runInTx(ctx, func(tx *sql.Tx) error {
+			hold, err := h.lockPendingHold(ctx, tx, id)
+			if err != nil {
+				return err
+			}
+			return h.releaseHold(ctx, tx, hold, HoldStatusExpired, "system")
+		})
+		if err != nil {
+			// The hold may have been captured or voided since it was selected
+			slog.WarnContext(ctx, "error expiring hold", "hold_id", id, "error", err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// lockPendingHold reads a hold and locks it until the end of the transaction, failing if
+// the hold has already been resolved
+func (h *HoldRepository) lockPendingHold(ctx context.Context, tx *sql.Tx, holdId string) (*Hold, error) {
+	hold := &Hold{Id: holdId}
+	var txnId sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		SELECT debit_account_id, credit_account_id, amount, captured_amount, currency, status, expires_at, transaction_id, created_at
+		FROM holds
+		WHERE id = $1
+		FOR UPDATE
+	`, holdId).Scan(&hold.DebitAccountId, &hold.CreditAccountId, &hold.Amount.Units, &hold.CapturedAmount.Units,
+		&hold.Amount.Currency, &hold.Status, &hold.ExpiresAt, &txnId, &hold.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrHoldNotFound, holdId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	hold.CapturedAmount.Currency = hold.Amount.Currency
+	hold.TransactionId = txnId.String
+
+	if hold.Status != HoldStatusPending {
+		return nil, fmt.Errorf("%w: hold %s is already %s", ErrHoldNotPending, holdId, hold.Status)
+	}
+	return hold, nil
+}
+
+// releaseHold returns a pending hold's reserved amount to the account's available balance
+// and resolves the hold with the given status
+func (h *HoldRepository) releaseHold(ctx context.Context, tx *sql.Tx, hold *Hold, status, userId string) error {
+	debitBalance, err := readAccountBalance(ctx, tx, hold.DebitAccountId)
+	if err != nil {
+		return err
+	}
+	if err := applyBalanceChanges(ctx, tx, balanceChange{balance: debitBalance, pendingDelta: -hold.Amount.Units}); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE holds SET status = $2, resolved_at = CURRENT_TIMESTAMP, updated_by = $3 WHERE id = $1",
+		hold.Id, status, userId)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while releasing hold", "error", err)
+		return err
+	}
+	hold.Status = status
+	return nil
+}
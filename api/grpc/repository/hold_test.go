@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestHoldRepository_CreateHold(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_withdraw_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	tests := []struct {
+		name            string
+		amount          money.Money
+		debitAccountId  string
+		creditAccountId string
+		expiresAt       time.Time
+		wantErr         bool
+		errIs           error
+	}{
+		{
+			name:            "successful hold",
+			amount:          usd(100),
+			debitAccountId:  "acct_1",
+			creditAccountId: "acct_3",
+			wantErr:         false,
+		},
+		{
+			name:            "held funds are no longer available",
+			amount:          usd(100),
+			debitAccountId:  "acct_1",
+			creditAccountId: "acct_3",
+			wantErr:         true,
+		},
+		{
+			name:            "expiry in the past",
+			amount:          usd(10),
+			debitAccountId:  "acct_1",
+			creditAccountId: "acct_3",
+			expiresAt:       time.Now().Add(-time.Hour),
+			wantErr:         true,
+		},
+		{
+			name:            "credit account does not exist",
+			amount:          usd(10),
+			debitAccountId:  "acct_1",
+			creditAccountId: "acct_6",
+			wantErr:         true,
+		},
+		{
+			name:            "hold on another user's account",
+			amount:          usd(10),
+			debitAccountId:  "acct_3",
+			creditAccountId: "acct_1",
+			wantErr:         true,
+			errIs:           ErrAccountNotOwned,
+		},
+		{
+			name:            "hold crediting an external account",
+			amount:          usd(10),
+			debitAccountId:  "acct_1",
+			creditAccountId: "acct_7",
+			wantErr:         true,
+			errIs:           ErrInvalidPosting,
+		},
+	}
+
+	holds := NewHoldRepository(db, NewTransactionRepository(db, "txn_", "le_"), "hold_")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hold, err := holds.CreateHold(context.Background(), tt.amount, "usr_1", tt.debitAccountId, tt.creditAccountId, tt.expiresAt)
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.errIs != nil {
+					assert.ErrorIs(t, err, tt.errIs)
+				}
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, HoldStatusPending, hold.Status)
+				assert.True(t, identifier.ID(hold.Id).Validate())
+			}
+		})
+	}
+
+	// The hold reduces available but not posted balance
+	balance, err := NewAccountRepository(db, "acct_").GetAccountBalance(context.Background(), "acct_1", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(150), balance.Amount.Units)
+	assert.Equal(t, int64(100), balance.Pending.Units)
+	assert.Equal(t, int64(50), balance.Available.Units)
+}
+
+func TestHoldRepository_Lifecycle(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_withdraw_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	accounts := NewAccountRepository(db, "acct_")
+	holds := NewHoldRepository(db, NewTransactionRepository(db, "txn_", "le_"), "hold_")
+
+	assertBalance := func(accountId string, posted, pending, available int64) {
+		t.Helper()
+		balance, err := accounts.GetAccountBalance(ctx, accountId, time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, posted, balance.Amount.Units, "posted")
+		assert.Equal(t, pending, balance.Pending.Units, "pending")
+		assert.Equal(t, available, balance.Available.Units, "available")
+	}
+
+	// acct_1 starts with 150 and acct_3, usr_2's internal account, with 0
+	captured, err := holds.CreateHold(ctx, usd(100), "usr_1", "acct_1", "acct_3", time.Time{})
+	assert.NoError(t, err)
+	voided, err := holds.CreateHold(ctx, usd(30), "usr_1", "acct_1", "acct_3", time.Time{})
+	assert.NoError(t, err)
+	assertBalance("acct_1", 150, 130, 20)
+
+	t.Run("capture more than held", func(t *testing.T) {
+		_, err := holds.CaptureHold(ctx, captured.Id, &money.Money{Units: 101, Currency: "USD"}, "usr_1")
+		assert.Error(t, err)
+	})
+
+	t.Run("partial capture", func(t *testing.T) {
+		hold, err := holds.CaptureHold(ctx, captured.Id, &money.Money{Units: 60, Currency: "USD"}, "usr_1")
+		assert.NoError(t, err)
+		assert.Equal(t, HoldStatusPosted, hold.Status)
+		assert.Equal(t, int64(60), hold.CapturedAmount.Units)
+		assert.NotEmpty(t, hold.TransactionId)
+		// the uncaptured 40 is released
+		assertBalance("acct_1", 90, 30, 60)
+		assertBalance("acct_3", 60, 0, 60)
+	})
+
+	t.Run("hold can only be captured once", func(t *testing.T) {
+		_, err := holds.CaptureHold(ctx, captured.Id, nil, "usr_1")
+		assert.Error(t, err)
+	})
+
+	t.Run("void", func(t *testing.T) {
+		hold, err := holds.VoidHold(ctx, voided.Id, "usr_1")
+		assert.NoError(t, err)
+		assert.Equal(t, HoldStatusVoided, hold.Status)
+		assertBalance("acct_1", 90, 0, 90)
+	})
+
+	t.Run("voided hold cannot be captured", func(t *testing.T) {
+		_, err := holds.CaptureHold(ctx, voided.Id, nil, "usr_1")
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown hold", func(t *testing.T) {
+		_, err := holds.VoidHold(ctx, "hold_missing", "usr_1")
+		assert.Error(t, err)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		hold, err := holds.CreateHold(ctx, usd(50), "usr_1", "acct_1", "acct_3", time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		assertBalance("acct_1", 90, 50, 40)
+
+		_, err = db.Exec("UPDATE holds SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 minute' WHERE id = $1", hold.Id)
+		assert.NoError(t, err)
+
+		_, err = holds.CaptureHold(ctx, hold.Id, nil, "usr_1")
+		assert.Error(t, err)
+
+		n, err := holds.ExpireHolds(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assertBalance("acct_1", 90, 0, 90)
+	})
+
+	// Every projected pending amount is backed by a pending hold
+	drift, err := accounts.RebuildBalances(ctx, true)
+	assert.NoError(t, err)
+	assert.Empty(t, drift)
+}
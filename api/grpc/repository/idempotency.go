@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when an idempotency key is used again with a
+	// different request than the one it was first used with
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+	// ErrIdempotencyKeyInProgress is returned when the request an idempotency key was first
+	// used with has not finished yet
+	ErrIdempotencyKeyInProgress = errors.New("request with this idempotency key is still in progress")
+)
+
+// IdempotencyRecord is the stored outcome of the first request made with an idempotency key.
+// Response is nil until that request completes.
+type IdempotencyRecord struct {
+	Caller      string
+	Key         string
+	Method      string
+	Fingerprint []byte
+	Response    []byte
+	// ClaimedAt tells apart successive claims on a key that expired in between
+	ClaimedAt time.Time
+}
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Claim records that a request with fingerprint is being made with the caller's idempotency
+// key. The first request claims the key and gets a record without a response. A retry of a
+// completed request gets the record with the original response to replay. The claim of a
+// request in flight lapses after lease, unless the request's changes were committed, and keys
+// that expired are claimed afresh.
+func (i *IdempotencyRepository) Claim(ctx context.Context, caller, key, method string, fingerprint []byte, lease time.Duration) (*IdempotencyRecord, error) {
+	record := &IdempotencyRecord{Caller: caller, Key: key}
+	err := i.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (caller, key, method, fingerprint, expires_at)
+		VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP + $5 * INTERVAL '1 microsecond')
+		ON CONFLICT (caller, key) DO UPDATE
+		SET method = EXCLUDED.method, fingerprint = EXCLUDED.fingerprint, response = NULL,
+			created_at = CURRENT_TIMESTAMP, completed_at = NULL, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+		RETURNING method, fingerprint, created_at
+	`, caller, key, method, fingerprint, lease.Microseconds()).Scan(&record.Method, &record.Fingerprint, &record.ClaimedAt)
+	if err == nil {
+		return record, nil
+	}
+	if err != sql.ErrNoRows {
+		slog.ErrorContext(ctx, "error while claiming idempotency key", "error", err)
+		return nil, err
+	}
+
+	// The key is held by an earlier request that has not expired
+	err = i.db.QueryRowContext(ctx, "SELECT method, fingerprint, response FROM idempotency_keys WHERE caller = $1 AND key = $2",
+		caller, key).Scan(&record.Method, &record.Fingerprint, &record.Response)
+	if err == sql.ErrNoRows {
+		// The earlier request failed and released the key between the two statements
+		return nil, fmt.Errorf("%w: %s", ErrIdempotencyKeyInProgress, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.Method != method || !bytes.Equal(record.Fingerprint, fingerprint) {
+		return nil, fmt.Errorf("%w: %s", ErrIdempotencyKeyReused, key)
+	}
+	if record.Response == nil {
+		return nil, fmt.Errorf("%w: %s", ErrIdempotencyKeyInProgress, key)
+	}
+	return record, nil
+}
+
+// Complete stores the response of the request that claimed an idempotency key, which is
+// replayed to retries until ttl from now
+func (i *IdempotencyRepository) Complete(ctx context.Context, record *IdempotencyRecord, response []byte, ttl time.Duration) error {
+	res, err := i.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET response = $4, completed_at = COALESCE(completed_at, CURRENT_TIMESTAMP), expires_at = CURRENT_TIMESTAMP + $5 * INTERVAL '1 microsecond'
+		WHERE caller = $1 AND key = $2 AND created_at = $3
+	`, record.Caller, record.Key, record.ClaimedAt, response, ttl.Microseconds())
+	if err != nil {
+		return fmt.Errorf("error completing idempotency key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error completing idempotency key: %s was claimed by another request", record.Key)
+	}
+	return nil
+}
+
+// Release gives up an idempotency key whose request failed, so that it can be retried. A key
+// whose request's changes were committed is kept.
+func (i *IdempotencyRepository) Release(ctx context.Context, record *IdempotencyRecord) error {
+	_, err := i.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE caller = $1 AND key = $2 AND created_at = $3 AND completed_at IS NULL",
+		record.Caller, record.Key, record.ClaimedAt)
+	if err != nil {
+		return fmt.Errorf("error releasing idempotency key: %w", err)
+	}
+	return nil
+}
+
+type idempotencyClaimKey struct{}
+
+type idempotencyClaim struct {
+	record *IdempotencyRecord
+	ttl    time.Duration
+}
+
+// WithIdempotencyClaim returns a context under which the transaction that commits a request's
+// changes also marks the idempotency key it claimed as used, keeping it for ttl. A key whose
+// changes are committed can no longer lapse or be released, even if storing the response
+// fails, so the request is never run twice.
+func WithIdempotencyClaim(ctx context.Context, record *IdempotencyRecord, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, idempotencyClaimKey{}, &idempotencyClaim{record: record, ttl: ttl})
+}
+
+// useIdempotencyClaim marks the idempotency key claimed in ctx, if any, as used by tx. If the
+// claim lapsed and the key was claimed by another request, tx must not commit.
+func useIdempotencyClaim(ctx context.Context, tx *sql.Tx) error {
+	claim, ok := ctx.Value(idempotencyClaimKey{}).(*idempotencyClaim)
+	if !ok {
+		return nil
+	}
+	res, err := tx.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET completed_at = COALESCE(completed_at, CURRENT_TIMESTAMP), expires_at = CURRENT_TIMESTAMP + $4 * INTERVAL '1 microsecond'
+		WHERE caller = $1 AND key = $2 AND created_at = $3
+	`, claim.record.Caller, claim.record.Key, claim.record.ClaimedAt, claim.ttl.Microseconds())
+	if err != nil {
+		return fmt.Errorf("error using idempotency key: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error using idempotency key: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ErrIdempotencyKeyInProgress, claim.record.Key)
+	}
+	return nil
+}
+
+// PurgeExpired deletes expired idempotency keys and returns how many were deleted
+func (i *IdempotencyRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	res, err := i.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP")
+	if err != nil {
+		return 0, fmt.Errorf("error purging idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
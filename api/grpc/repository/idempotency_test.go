@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestIdempotencyRepository(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	keys := NewIdempotencyRepository(db)
+	method := "/api.ApiService/WithdrawFunds"
+	fingerprint := []byte("request")
+
+	var first *IdempotencyRecord
+	t.Run("first request claims the key", func(t *testing.T) {
+		var err error
+		first, err = keys.Claim(ctx, "usr_1", "key_1", method, fingerprint, time.Hour)
+		assert.NoError(t, err)
+		assert.Nil(t, first.Response)
+	})
+
+	t.Run("retry while the first request is in flight", func(t *testing.T) {
+		_, err := keys.Claim(ctx, "usr_1", "key_1", method, fingerprint, time.Hour)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+	})
+
+	t.Run("retry after the first request completed replays its response", func(t *testing.T) {
+		assert.NoError(t, keys.Complete(ctx, first, []byte("response"), time.Hour))
+		record, err := keys.Claim(ctx, "usr_1", "key_1", method, fingerprint, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("response"), record.Response)
+	})
+
+	t.Run("key reused with a different request", func(t *testing.T) {
+		_, err := keys.Claim(ctx, "usr_1", "key_1", method, []byte("another request"), time.Hour)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+		_, err = keys.Claim(ctx, "usr_1", "key_1", "/api.ApiService/TransferFunds", fingerprint, time.Hour)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	})
+
+	var scoped *IdempotencyRecord
+	t.Run("keys are scoped to the caller", func(t *testing.T) {
+		var err error
+		scoped, err = keys.Claim(ctx, "usr_2", "key_1", method, []byte("another request"), time.Hour)
+		assert.NoError(t, err)
+		assert.Nil(t, scoped.Response)
+	})
+
+	t.Run("released key can be claimed again", func(t *testing.T) {
+		assert.NoError(t, keys.Release(ctx, scoped))
+		record, err := keys.Claim(ctx, "usr_2", "key_1", method, fingerprint, time.Hour)
+		assert.NoError(t, err)
+		assert.Nil(t, record.Response)
+	})
+
+	t.Run("expired key is claimed afresh", func(t *testing.T) {
+		record, err := keys.Claim(ctx, "usr_3", "key_1", method, fingerprint, -time.Second)
+		assert.NoError(t, err)
+		assert.NoError(t, keys.Complete(ctx, record, []byte("response"), -time.Second))
+
+		record, err = keys.Claim(ctx, "usr_3", "key_1", method, []byte("another request"), time.Hour)
+		assert.NoError(t, err)
+		assert.Nil(t, record.Response)
+	})
+
+	t.Run("the transaction that commits the request's changes marks its key as used", func(t *testing.T) {
+		record, err := keys.Claim(ctx, "usr_5", "key_1", method, fingerprint, time.Minute)
+		assert.NoError(t, err)
+		err = runInTx(WithIdempotencyClaim(ctx, record, time.Hour), db, nil, DefaultRetryPolicy, func(tx *sql.Tx) error { return nil })
+		assert.NoError(t, err)
+
+		// Storing the response failed, but the key is not released for the request to run again
+		assert.NoError(t, keys.Release(ctx, record))
+		_, err = keys.Claim(ctx, "usr_5", "key_1", method, fingerprint, time.Minute)
+		assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+		var expiresAt time.Time
+		assert.NoError(t, db.QueryRow("SELECT expires_at FROM idempotency_keys WHERE caller = 'usr_5'").Scan(&expiresAt))
+		assert.True(t, expiresAt.After(time.Now().Add(30*time.Minute)))
+	})
+
+	t.Run("a request whose claim lapsed and was taken by a retry does not commit", func(t *testing.T) {
+		lapsed, err := keys.Claim(ctx, "usr_6", "key_1", method, fingerprint, -time.Second)
+		assert.NoError(t, err)
+		retry, err := keys.Claim(ctx, "usr_6", "key_1", method, fingerprint, time.Minute)
+		assert.NoError(t, err)
+
+		err = runInTx(WithIdempotencyClaim(ctx, lapsed, time.Hour), db, nil, DefaultRetryPolicy, func(tx *sql.Tx) error { return nil })
+		assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+		assert.Error(t, keys.Complete(ctx, lapsed, []byte("response"), time.Hour))
+		// Nor can it release the retry's claim
+		assert.NoError(t, keys.Release(ctx, lapsed))
+		assert.NoError(t, keys.Complete(ctx, retry, []byte("response"), time.Hour))
+	})
+
+	t.Run("purge expired keys", func(t *testing.T) {
+		_, err := keys.Claim(ctx, "usr_4", "key_1", method, fingerprint, -time.Second)
+		assert.NoError(t, err)
+		n, err := keys.PurgeExpired(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+}
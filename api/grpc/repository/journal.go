@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/currency"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+)
+
+// JournalLeg is one debit or credit of a journal entry
+type JournalLeg struct {
+	AccountId string
+	// Direction is either "debit" or "credit"
+	Direction string
+	Amount    money.Money
+}
+
+// PostJournalEntry posts any number of debits and credits as a single transaction, e.g. a
+// payment split between a merchant and a fee account. Debits must equal credits in every
+// currency, and every account whose balance decreases must have sufficient funds. Either
+// all legs are posted or none are.
+func (t *TransactionRepository) PostJournalEntry(ctx context.Context, legs []JournalLeg, userId string) (string, error) {
+	entry, amount, err := journalLegs(legs)
+	if err != nil {
+		return "", err
+	}
+
+	var txnId string
+	err = t.runInTx(ctx, func(tx *sql.Tx) error {
+		for _, leg := range entry {
+			account, err := readPostingAccount(ctx, tx, leg.accountId)
+			if err != nil {
+				return err
+			}
+			if err := account.allows(leg.direction); err != nil {
+				return err
+			}
+			if account.currency != leg.currency.Code {
+				return fmt.Errorf("%w: leg is in %s but account %s is in %s",
+					money.ErrCurrencyMismatch, leg.currency.Code, leg.accountId, account.currency)
+			}
+		}
+
+		changes, err := legBalanceChanges(ctx, tx, entry)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if change.postedDelta < 0 && !t.checkSufficientBalance(change.balance, -change.postedDelta) {
+				slog.Error("insufficient balance", "account_id", change.balance.accountId)
+				return fmt.Errorf("%w in account %s", ErrInsufficientFunds, change.balance.accountId)
+			}
+		}
+
+		txnId, err = t.insertTransaction(ctx, tx, amount, entry, userId, "", "")
+		if err != nil {
+			return err
+		}
+
+		return applyBalanceChanges(ctx, tx, changes...)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return txnId, nil
+}
+
+// journalLegs validates the legs of a journal entry and returns them as ledger legs along
+// with the transaction amount: the total debited when the entry is in a single currency,
+// or zero with no currency when it spans several
+func journalLegs(legs []JournalLeg) ([]ledgerLeg, money.Money, error) {
+	if len(legs) < 2 {
+		return nil, money.Money{}, fmt.Errorf("%w: a journal entry needs at least two legs, got %d", ErrInvalidRequest, len(legs))
+	}
+
+	entry := make([]ledgerLeg, 0, len(legs))
+	debits := make(map[string]int64)
+	credits := make(map[string]int64)
+	var codes []string
+	for i, leg := range legs {
+		if leg.AccountId == "" {
+			return nil, money.Money{}, fmt.Errorf("%w: leg %d has no account", ErrInvalidRequest, i)
+		}
+		if leg.Amount.Units <= 0 {
+			return nil, money.Money{}, fmt.Errorf("%w: leg %d must be greater than zero", money.ErrInvalidAmount, i)
+		}
+		cur, err := currency.Lookup(leg.Amount.Currency)
+		if err != nil {
+			return nil, money.Money{}, err
+		}
+
+		totals := credits
+		switch leg.Direction {
+		case "debit":
+			totals = debits
+		case "credit":
+		default:
+			return nil, money.Money{}, fmt.Errorf("%w: leg %d has invalid direction %q", ErrInvalidRequest, i, leg.Direction)
+		}
+		if _, ok := debits[cur.Code]; !ok {
+			if _, ok := credits[cur.Code]; !ok {
+				codes = append(codes, cur.Code)
+			}
+		}
+		if totals[cur.Code] > math.MaxInt64-leg.Amount.Units {
+			return nil, money.Money{}, fmt.Errorf("%w: %s legs overflow", money.ErrInvalidAmount, cur.Code)
+		}
+		totals[cur.Code] += leg.Amount.Units
+
+		entry = append(entry, ledgerLeg{accountId: leg.AccountId, direction: leg.Direction, units: leg.Amount.Units, currency: cur})
+	}
+
+	for _, code := range codes {
+		if debits[code] != credits[code] {
+			return nil, money.Money{}, fmt.Errorf("%w: journal entry is unbalanced in %s: debits %s, credits %s", ErrInvalidRequest,
+				code, money.Money{Units: debits[code], Currency: code}, money.Money{Units: credits[code], Currency: code})
+		}
+	}
+
+	if len(codes) > 1 {
+		return entry, money.Money{}, nil
+	}
+	return entry, money.Money{Units: debits[codes[0]], Currency: codes[0]}, nil
+}
@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func eur(units int64) money.Money {
+	return money.Money{Units: units, Currency: "EUR"}
+}
+
+func TestJournalLegs(t *testing.T) {
+	tests := []struct {
+		name       string
+		legs       []JournalLeg
+		wantAmount money.Money
+		wantErr    bool
+	}{
+		{
+			name: "split payment",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(1000)},
+				{AccountId: "acct_2", Direction: "credit", Amount: usd(970)},
+				{AccountId: "acct_4", Direction: "credit", Amount: usd(30)},
+			},
+			wantAmount: usd(1000),
+		},
+		{
+			name: "balanced in each of two currencies",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(100)},
+				{AccountId: "acct_4", Direction: "credit", Amount: usd(100)},
+				{AccountId: "acct_3", Direction: "debit", Amount: eur(90)},
+				{AccountId: "acct_5", Direction: "credit", Amount: eur(90)},
+			},
+			wantAmount: money.Money{},
+		},
+		{
+			name: "balanced in total but not per currency",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(100)},
+				{AccountId: "acct_5", Direction: "credit", Amount: eur(100)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "debits exceed credits",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(100)},
+				{AccountId: "acct_2", Direction: "credit", Amount: usd(99)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "single leg",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(100)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "zero amount leg",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(0)},
+				{AccountId: "acct_2", Direction: "credit", Amount: usd(0)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid direction",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(100)},
+				{AccountId: "acct_2", Direction: "deposit", Amount: usd(100)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unsupported currency",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: money.Money{Units: 100, Currency: "ABC"}},
+				{AccountId: "acct_2", Direction: "credit", Amount: money.Money{Units: 100, Currency: "ABC"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, amount, err := journalLegs(tt.legs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.legs), len(entry))
+			assert.Equal(t, tt.wantAmount, amount)
+		})
+	}
+}
+
+func TestTransactionRepository_PostJournalEntry(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_multi_currency.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	accounts := NewAccountRepository(db, "acct_")
+	repo := NewTransactionRepository(db, "txn_", "le_")
+
+	assertPosted := func(accountId string, posted int64) {
+		t.Helper()
+		balance, err := accounts.GetAccountBalance(ctx, accountId, time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, posted, balance.Amount.Units)
+	}
+
+	// acct_1 holds 10000 USD and acct_3 10000 EUR
+	tests := []struct {
+		name    string
+		legs    []JournalLeg
+		wantErr bool
+	}{
+		{
+			name: "split payment with a fee",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(1000)},
+				{AccountId: "acct_2", Direction: "credit", Amount: usd(970)},
+				{AccountId: "acct_4", Direction: "credit", Amount: usd(30)},
+			},
+		},
+		{
+			name: "one debited account has insufficient funds",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(100)},
+				{AccountId: "acct_2", Direction: "debit", Amount: usd(971)},
+				{AccountId: "acct_4", Direction: "credit", Amount: usd(1071)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "leg currency does not match the account",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: eur(100)},
+				{AccountId: "acct_5", Direction: "credit", Amount: eur(100)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "leg account does not exist",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(100)},
+				{AccountId: "acct_2", Direction: "credit", Amount: usd(50)},
+				{AccountId: "acct_6", Direction: "credit", Amount: usd(50)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "two currencies in one entry",
+			legs: []JournalLeg{
+				{AccountId: "acct_1", Direction: "debit", Amount: usd(100)},
+				{AccountId: "acct_4", Direction: "credit", Amount: usd(100)},
+				{AccountId: "acct_3", Direction: "debit", Amount: eur(90)},
+				{AccountId: "acct_5", Direction: "credit", Amount: eur(90)},
+			},
+		},
+	}
+
+	var multiCurrencyId string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txnId, err := repo.PostJournalEntry(ctx, tt.legs, "usr_1")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, identifier.ID(txnId).Validate())
+
+			var entries int
+			err = db.QueryRow("SELECT COUNT(*) FROM ledger_entries WHERE transaction_id = $1", txnId).Scan(&entries)
+			assert.NoError(t, err)
+			assert.Equal(t, len(tt.legs), entries)
+			multiCurrencyId = txnId
+		})
+	}
+
+	// Failed entries leave no trace on any account
+	assertPosted("acct_1", 10000-1000-100)
+	assertPosted("acct_2", 970)
+	assertPosted("acct_4", -10000+30+100)
+	assertPosted("acct_3", 10000-90)
+	assertPosted("acct_5", -10000+90)
+
+	t.Run("journal entries can only be reversed in full", func(t *testing.T) {
+		partial := usd(10)
+		_, err := repo.ReverseTransaction(ctx, multiCurrencyId, &partial, "usr_1")
+		assert.Error(t, err)
+
+		_, err = repo.ReverseTransaction(ctx, multiCurrencyId, nil, "usr_1")
+		assert.NoError(t, err)
+		assertPosted("acct_1", 10000-1000)
+		assertPosted("acct_3", 10000)
+	})
+}
@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Types of the events recorded in the ledger_events outbox
+const (
+	EventTransactionCreated  = "transaction.created"
+	EventTransactionReversed = "transaction.reversed"
+	EventAccountFrozen       = "account.frozen"
+	EventAccountUnfrozen     = "account.unfrozen"
+	EventAccountClosed       = "account.closed"
+	EventBalanceChanged      = "balance.changed"
+)
+
+// EventTypes are the types of every event recorded in the outbox
+var EventTypes = []string{
+	EventTransactionCreated,
+	EventTransactionReversed,
+	EventAccountFrozen,
+	EventAccountUnfrozen,
+	EventAccountClosed,
+	EventBalanceChanged,
+}
+
+// accountStateEvents are the events recorded when an account moves into a state
+var accountStateEvents = map[string]string{
+	AccountStateFrozen: EventAccountFrozen,
+	AccountStateOpen:   EventAccountUnfrozen,
+	AccountStateClosed: EventAccountClosed,
+}
+
+// LedgerEvent is a change to the ledger, recorded in the ledger_events outbox in the same
+// database transaction as the change itself
+type LedgerEvent struct {
+	Id int64
+	// Position orders events by when the change they record was committed. It is given to
+	// an event after its transaction commits, by the first reader of the outbox to see it.
+	Position int64
+	Type     string
+	// AggregateId is the transaction or account the event is about
+	AggregateId string
+	Payload     json.RawMessage
+	CreatedAt   time.Time
+}
+
+type eventLeg struct {
+	AccountId string `json:"account_id"`
+	Direction string `json:"direction"`
+	Amount    int64  `json:"amount"`
+	Currency  string `json:"currency"`
+}
+
+type transactionCreatedEvent struct {
+	TransactionId string `json:"transaction_id"`
+	Amount        int64  `json:"amount"`
+	// Currency is empty for journal entries that span several currencies
+	Currency              string     `json:"currency,omitempty"`
+	CreatedBy             string     `json:"created_by"`
+	ReversesTransactionId string     `json:"reverses_transaction_id,omitempty"`
+	PaymentMethodId       string     `json:"payment_method_id,omitempty"`
+	Legs                  []eventLeg `json:"legs"`
+}
+
+type transactionReversedEvent struct {
+	TransactionId  string `json:"transaction_id"`
+	ReversalId     string `json:"reversal_id"`
+	Amount         int64  `json:"amount"`
+	ReversedAmount int64  `json:"reversed_amount"`
+	Currency       string `json:"currency"`
+	Status         string `json:"status"`
+	ReversedBy     string `json:"reversed_by"`
+}
+
+type accountStateEvent struct {
+	AccountId     string `json:"account_id"`
+	AccountState  string `json:"account_state"`
+	PreviousState string `json:"previous_state"`
+	ChangedBy     string `json:"changed_by"`
+}
+
+type balanceChangedEvent struct {
+	AccountId string `json:"account_id"`
+	Currency  string `json:"currency"`
+	Posted    int64  `json:"posted"`
+	Pending   int64  `json:"pending"`
+	Available int64  `json:"available"`
+	// Version increases with every change to the account's balance
+	Version int64 `json:"version"`
+}
+
+// recordEvent writes an event to the outbox as part of the caller's transaction, so the
+// event is published if and only if the change it records is committed
+func recordEvent(ctx context.Context, tx *sql.Tx, eventType, aggregateId string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error encoding %s event: %w", eventType, err)
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO ledger_events (event_type, aggregate_id, payload) VALUES ($1, $2, $3)",
+		eventType, aggregateId, body)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while recording ledger event", "event_type", eventType, "error", err)
+		return fmt.Errorf("error recording %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+type LedgerEventRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerEventRepository(db *sql.DB) *LedgerEventRepository {
+	return &LedgerEventRepository{db: db}
+}
+
+// RelayLease is how long a relay holds a sink while it publishes a batch to it. Publishing a
+// batch should take less; one that takes longer may be published to the sink twice.
+const RelayLease = time.Minute
+
+// RelayBatch hands the next batch of events after a sink's checkpoint to publish, in commit
+// order, and advances the checkpoint past them once publish succeeds. It returns how many
+// events were published.
+//
+// The sink is leased for RelayLease while its batch is published, so only one relay
+// publishes to a sink at a time; a relay that finds it leased publishes nothing. No database
+// transaction is held open while publishing. The checkpoint is advanced with a
+// compare-and-set, so it never moves backwards even if a lease lapsed and another relay
+// published in the meantime. If publish fails, or the checkpoint cannot be saved after it
+// succeeded, the same events are handed out again on the next call, so sinks receive every
+// event at least once.
+func (l *LedgerEventRepository) RelayBatch(ctx context.Context, sink string, limit int, publish func([]LedgerEvent) error) (int, error) {
+	_, err := l.db.ExecContext(ctx, "INSERT INTO ledger_event_checkpoints (sink) VALUES ($1) ON CONFLICT (sink) DO NOTHING", sink)
+	if err != nil {
+		return 0, fmt.Errorf("error creating checkpoint for sink %s: %w", sink, err)
+	}
+	var checkpoint int64
+	var leasedUntil time.Time
+	err = l.db.QueryRowContext(ctx, `
+		UPDATE ledger_event_checkpoints SET leased_until = CURRENT_TIMESTAMP + $2 * INTERVAL '1 microsecond'
+		WHERE sink = $1 AND (leased_until IS NULL OR leased_until <= CURRENT_TIMESTAMP)
+		RETURNING position, leased_until
+	`, sink, RelayLease.Microseconds()).Scan(&checkpoint, &leasedUntil)
+	if err == sql.ErrNoRows {
+		// Another relay is publishing to this sink
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error leasing checkpoint for sink %s: %w", sink, err)
+	}
+
+	err = l.assignPositions(ctx)
+	var events []LedgerEvent
+	if err == nil {
+		events, err = readEvents(ctx, l.db, checkpoint, limit)
+	}
+	if err == nil && len(events) > 0 {
+		err = publish(events)
+		if err != nil {
+			err = fmt.Errorf("error publishing to sink %s: %w", sink, err)
+		}
+	}
+	if err != nil || len(events) == 0 {
+		l.releaseLease(ctx, sink, leasedUntil)
+		return 0, err
+	}
+
+	// The lease is given back with the checkpoint, unless it lapsed and was taken over
+	res, err := l.db.ExecContext(context.WithoutCancel(ctx), `
+		UPDATE ledger_event_checkpoints
+		SET position = $3, updated_at = CURRENT_TIMESTAMP,
+			leased_until = CASE WHEN leased_until = $4 THEN NULL ELSE leased_until END
+		WHERE sink = $1 AND position = $2
+	`, sink, checkpoint, events[len(events)-1].Position, leasedUntil)
+	if err != nil {
+		return 0, fmt.Errorf("error saving checkpoint for sink %s: %w", sink, err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		// The events were published again, which at least once delivery allows
+		slog.WarnContext(ctx, "checkpoint was advanced by another relay", "sink", sink)
+		l.releaseLease(ctx, sink, leasedUntil)
+	}
+	return len(events), nil
+}
+
+// releaseLease gives back a relay's lease on a sink, unless it lapsed and was taken over
+func (l *LedgerEventRepository) releaseLease(ctx context.Context, sink string, leasedUntil time.Time) {
+	_, err := l.db.ExecContext(context.WithoutCancel(ctx), "UPDATE ledger_event_checkpoints SET leased_until = NULL WHERE sink = $1 AND leased_until = $2",
+		sink, leasedUntil)
+	if err != nil {
+		// The lease lapses on its own
+		slog.ErrorContext(ctx, "error releasing checkpoint lease", "sink", sink, "error", err)
+	}
+}
+
+// LatestPosition returns the position of the last committed event, or 0 if there are none.
+// Every event up to it is visible from then on.
+func (l *LedgerEventRepository) LatestPosition(ctx context.Context) (int64, error) {
+	if err := l.assignPositions(ctx); err != nil {
+		return 0, err
+	}
+	var position int64
+	err := l.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(position), 0) FROM ledger_events").Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("error reading latest ledger event position: %w", err)
+	}
+	return position, nil
+}
+
+// assignPositions gives every committed event that has no position yet the next positions,
+// in the order the events were written. Events are committed without a position so that
+// transactions recording them do not have to commit one at a time; instead positions are
+// given out by one reader at a time, under a lock held until its positions are visible.
+// Each reader therefore sees every position given out before it, and an event that is
+// committed later always gets a higher position than those already visible.
+//
+// Listeners on the ledger_events channel are notified when positions are given out, so
+// watchers woken by an event's commit before it had a position read it straight after.
+func (l *LedgerEventRepository) assignPositions(ctx context.Context) error {
+	err := runInTx(ctx, l.db, nil, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		// The lock is taken before the update so that the update sees the positions given
+		// out by the reader that held it last
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('ledger_events'))"); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `
+			UPDATE ledger_events e SET position = unpositioned.position
+			FROM (
+				SELECT id, nextval('ledger_events_position_seq') AS position
+				FROM (SELECT id FROM ledger_events WHERE position IS NULL ORDER BY id) ordered
+			) unpositioned
+			WHERE e.id = unpositioned.id
+		`)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "SELECT pg_notify('ledger_events', '')")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error assigning ledger event positions: %w", err)
+	}
+	return nil
+}
+
+// ReadAccountEvents reads, in commit order, up to limit events of the given types that are
+// about an account and were committed at positions after `after` and up to `upTo`. An event
+// about a transaction is about every account the transaction has a leg in.
+func (l *LedgerEventRepository) ReadAccountEvents(ctx context.Context, accountId string, eventTypes []string, after, upTo int64, limit int) ([]LedgerEvent, error) {
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT e.id, e.position, e.event_type, e.aggregate_id, e.payload, e.created_at
+		FROM ledger_events e
+		WHERE e.position > $1 AND e.position <= $2 AND e.event_type = ANY($3)
+			AND (e.aggregate_id = $4 OR EXISTS (
+				SELECT 1 FROM ledger_entries le WHERE le.transaction_id = e.aggregate_id AND le.account_id = $4
+			))
+		ORDER BY e.position
+		LIMIT $5
+	`, after, upTo, pq.Array(eventTypes), accountId, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying ledger events of account %s: %w", accountId, err)
+	}
+	return scanEvents(rows)
+}
+
+// readEvents reads up to limit committed events after a position, in commit order
+func readEvents(ctx context.Context, q Querier, after int64, limit int) ([]LedgerEvent, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, position, event_type, aggregate_id, payload, created_at
+		FROM ledger_events
+		WHERE position > $1
+		ORDER BY position
+		LIMIT $2
+	`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying ledger events: %w", err)
+	}
+	return scanEvents(rows)
+}
+
+func scanEvents(rows *sql.Rows) ([]LedgerEvent, error) {
+	defer rows.Close()
+
+	var events []LedgerEvent
+	for rows.Next() {
+		var e LedgerEvent
+		if err := rows.Scan(&e.Id, &e.Position, &e.Type, &e.AggregateId, &e.Payload, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning ledger event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ledger events: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestLedgerEventRepository_RelayBatch(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_deposit_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	transactions := NewTransactionRepository(db, "txn_", "le_")
+	accounts := NewAccountRepository(db, "acct_")
+	events := NewLedgerEventRepository(db)
+
+	relayed := func(t *testing.T, sink string) []LedgerEvent {
+		t.Helper()
+		var got []LedgerEvent
+		n, err := events.RelayBatch(ctx, sink, 100, func(batch []LedgerEvent) error {
+			got = append(got, batch...)
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, len(got), n)
+		return got
+	}
+
+	txnId, err := transactions.DepositFunds(ctx, usd(100), "usr_1", "acct_2", "acct_1", "")
+	assert.NoError(t, err)
+	// A posting that fails records nothing: acct_3 does not exist
+	_, err = transactions.DepositFunds(ctx, usd(100), "usr_1", "acct_2", "acct_3", "")
+	assert.Error(t, err)
+
+	t.Run("a posting records its transaction and balance changes", func(t *testing.T) {
+		got := relayed(t, "test")
+		if !assert.Len(t, got, 3) {
+			return
+		}
+		assert.Equal(t, EventTransactionCreated, got[0].Type)
+		assert.Equal(t, txnId, got[0].AggregateId)
+		var created transactionCreatedEvent
+		assert.NoError(t, json.Unmarshal(got[0].Payload, &created))
+		assert.Equal(t, int64(100), created.Amount)
+		assert.Equal(t, "usr_1", created.CreatedBy)
+		assert.Len(t, created.Legs, 2)
+
+		// Balances are updated in account id order
+		assert.Equal(t, EventBalanceChanged, got[1].Type)
+		assert.Equal(t, "acct_1", got[1].AggregateId)
+		var balance balanceChangedEvent
+		assert.NoError(t, json.Unmarshal(got[1].Payload, &balance))
+		assert.Equal(t, int64(100), balance.Posted)
+		assert.Equal(t, "USD", balance.Currency)
+		assert.Equal(t, EventBalanceChanged, got[2].Type)
+		assert.Equal(t, "acct_2", got[2].AggregateId)
+
+		for i := 1; i < len(got); i++ {
+			assert.Greater(t, got[i].Position, got[i-1].Position)
+		}
+	})
+
+	t.Run("the checkpoint advances past relayed events", func(t *testing.T) {
+		assert.Empty(t, relayed(t, "test"))
+		// Every sink has its own checkpoint
+		assert.Len(t, relayed(t, "other"), 3)
+	})
+
+	t.Run("a failed publish is retried", func(t *testing.T) {
+		_, err := accounts.FreezeAccount(ctx, "acct_1", "usr_1")
+		assert.NoError(t, err)
+
+		n, err := events.RelayBatch(ctx, "test", 100, func([]LedgerEvent) error {
+			return errors.New("sink is down")
+		})
+		assert.Error(t, err)
+		assert.Zero(t, n)
+
+		got := relayed(t, "test")
+		if assert.Len(t, got, 1) {
+			assert.Equal(t, EventAccountFrozen, got[0].Type)
+			var frozen accountStateEvent
+			assert.NoError(t, json.Unmarshal(got[0].Payload, &frozen))
+			assert.Equal(t, AccountStateOpen, frozen.PreviousState)
+			assert.Equal(t, AccountStateFrozen, frozen.AccountState)
+		}
+	})
+
+	t.Run("only one relay publishes to a sink at a time", func(t *testing.T) {
+		n, err := events.RelayBatch(ctx, "leased", 100, func(batch []LedgerEvent) error {
+			// The sink is leased while its batch is published, outside any transaction
+			assert.Empty(t, relayed(t, "leased"))
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 4, n)
+
+		// A sink leased by another relay is skipped until the lease lapses
+		_, err = accounts.UnfreezeAccount(ctx, "acct_1", "usr_1")
+		assert.NoError(t, err)
+		_, err = db.Exec("UPDATE ledger_event_checkpoints SET leased_until = CURRENT_TIMESTAMP + INTERVAL '1 hour' WHERE sink = 'leased'")
+		assert.NoError(t, err)
+		assert.Empty(t, relayed(t, "leased"))
+		_, err = db.Exec("UPDATE ledger_event_checkpoints SET leased_until = CURRENT_TIMESTAMP - INTERVAL '1 second' WHERE sink = 'leased'")
+		assert.NoError(t, err)
+		assert.Len(t, relayed(t, "leased"), 1)
+	})
+
+	t.Run("the checkpoint never moves backwards", func(t *testing.T) {
+		var ahead int64
+		n, err := events.RelayBatch(ctx, "behind", 100, func(batch []LedgerEvent) error {
+			// Another relay whose lease lapsed has already moved the checkpoint on
+			ahead = batch[len(batch)-1].Position + 1000
+			_, err := db.Exec("UPDATE ledger_event_checkpoints SET position = $1 WHERE sink = 'behind'", ahead)
+			return err
+		})
+		assert.NoError(t, err)
+		assert.NotZero(t, n)
+
+		var position int64
+		var leasedUntil sql.NullTime
+		assert.NoError(t, db.QueryRow("SELECT position, leased_until FROM ledger_event_checkpoints WHERE sink = 'behind'").
+			Scan(&position, &leasedUntil))
+		assert.Equal(t, ahead, position)
+		assert.False(t, leasedUntil.Valid)
+	})
+
+	t.Run("an event committed later is given a later position", func(t *testing.T) {
+		relayed(t, "late")
+
+		// The event is written before the deposit's but committed after it
+		tx, err := db.BeginTx(ctx, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer tx.Rollback()
+		assert.NoError(t, recordEvent(ctx, tx, EventAccountFrozen, "acct_4", accountStateEvent{AccountId: "acct_4"}))
+		_, err = transactions.DepositFunds(ctx, usd(100), "usr_1", "acct_2", "acct_1", "")
+		assert.NoError(t, err)
+
+		var unpositioned int
+		assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM ledger_events WHERE position IS NULL").Scan(&unpositioned))
+		assert.Equal(t, 3, unpositioned, "events are committed without a position")
+		deposit := relayed(t, "late")
+		assert.Len(t, deposit, 3)
+
+		assert.NoError(t, tx.Commit())
+		late := relayed(t, "late")
+		if assert.Len(t, late, 1) && assert.NotEmpty(t, deposit) {
+			assert.Equal(t, "acct_4", late[0].AggregateId)
+			assert.Less(t, late[0].Id, deposit[0].Id)
+			assert.Greater(t, late[0].Position, deposit[len(deposit)-1].Position)
+		}
+	})
+}
+
+func TestLedgerEventRepository_ReadAccountEvents(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_deposit_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	transactions := NewTransactionRepository(db, "txn_", "le_")
+	events := NewLedgerEventRepository(db)
+
+	latest, err := events.LatestPosition(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, latest)
+
+	txnId, err := transactions.DepositFunds(ctx, usd(100), "usr_1", "acct_2", "acct_1", "")
+	assert.NoError(t, err)
+	latest, err = events.LatestPosition(ctx)
+	assert.NoError(t, err)
+
+	transactionEvents := []string{EventTransactionCreated, EventTransactionReversed}
+	tests := []struct {
+		name          string
+		accountId     string
+		eventTypes    []string
+		expectedTypes []string
+	}{
+		{"transaction events of the credited account", "acct_1", transactionEvents, []string{EventTransactionCreated}},
+		{"transaction events of the debited account", "acct_2", transactionEvents, []string{EventTransactionCreated}},
+		{"balance events are about one account", "acct_1", []string{EventBalanceChanged}, []string{EventBalanceChanged}},
+		{"an account the transaction did not touch", "acct_4", transactionEvents, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := events.ReadAccountEvents(ctx, tt.accountId, tt.eventTypes, 0, latest, 10)
+			assert.NoError(t, err)
+			var types []string
+			for _, e := range got {
+				types = append(types, e.Type)
+				if e.Type == EventTransactionCreated {
+					assert.Equal(t, txnId, e.AggregateId)
+				} else {
+					assert.Equal(t, tt.accountId, e.AggregateId)
+				}
+			}
+			assert.Equal(t, tt.expectedTypes, types)
+		})
+	}
+
+	t.Run("events outside the positions are not read", func(t *testing.T) {
+		got, err := events.ReadAccountEvents(ctx, "acct_1", transactionEvents, latest, latest+10, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+
+		got, err = events.ReadAccountEvents(ctx, "acct_2", []string{EventBalanceChanged}, 0, latest-1, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, got, "acct_2's balance changed last")
+	})
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// postingKind is the kind of money movement a two-legged posting makes, which decides the
+// accounts it may move money between
+type postingKind string
+
+const (
+	// postingDeposit moves money from a user's external account into their internal account
+	postingDeposit postingKind = "deposit"
+	// postingWithdrawal moves money from a user's internal account out to their external account
+	postingWithdrawal postingKind = "withdrawal"
+	// postingTransfer moves money from a user's internal account to any internal account
+	postingTransfer postingKind = "transfer"
+)
+
+// linkedAccounts are the ledger accounts created for a user when they sign up
+type linkedAccounts struct {
+	internal string
+	external string
+}
+
+// owns reports whether an account is one of the user's linked accounts
+func (l linkedAccounts) owns(accountId string) bool {
+	return accountId != "" && (accountId == l.internal || accountId == l.external)
+}
+
+// readLinkedAccounts reads an active user's linked accounts, locking the user against
+// concurrent changes, such as being deactivated, for the rest of the transaction
+func readLinkedAccounts(ctx context.Context, tx *sql.Tx, userId string) (linkedAccounts, error) {
+	var internal, external sql.NullString
+	var state string
+	err := tx.QueryRowContext(ctx, `
+		SELECT int_ledger_account_id, ext_ledger_account_id, user_state
+		FROM users
+		WHERE id = $1
+		FOR SHARE
+	`, userId).Scan(&internal, &external, &state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return linkedAccounts{}, fmt.Errorf("%w: %s", ErrUserNotFound, userId)
+	}
+	if err != nil {
+		return linkedAccounts{}, fmt.Errorf("error reading linked accounts: %w", err)
+	}
+	if state != UserStateActive {
+		return linkedAccounts{}, fmt.Errorf("%w: %s", ErrUserDeactivated, userId)
+	}
+	return linkedAccounts{internal: internal.String, external: external.String}, nil
+}
+
+// isInternalAccount reports whether an account is some user's internal account
+func isInternalAccount(ctx context.Context, tx *sql.Tx, accountId string) (bool, error) {
+	var internal bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE int_ledger_account_id = $1)
+	`, accountId).Scan(&internal)
+	if err != nil {
+		return false, fmt.Errorf("error checking account %s: %w", accountId, err)
+	}
+	return internal, nil
+}
+
+// checkLinkedAccounts checks that a posting moves money between accounts the user may use
+// for it. Deposits and withdrawals must go between the user's own external and internal
+// accounts, and transfers must go from the user's internal account to an internal account.
+func checkLinkedAccounts(ctx context.Context, tx *sql.Tx, kind postingKind, userId, debitedAccountId, creditedAccountId string) error {
+	links, err := readLinkedAccounts(ctx, tx, userId)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case postingDeposit, postingWithdrawal:
+		for _, id := range []string{debitedAccountId, creditedAccountId} {
+			if !links.owns(id) {
+				return fmt.Errorf("%w: account %s is not linked to user %s", ErrAccountNotOwned, id, userId)
+			}
+		}
+		from, to := links.external, links.internal
+		if kind == postingWithdrawal {
+			from, to = links.internal, links.external
+		}
+		if debitedAccountId != from || creditedAccountId != to {
+			return fmt.Errorf("%w: a %s must move money from %s to %s", ErrInvalidPosting, kind, from, to)
+		}
+	case postingTransfer:
+		if debitedAccountId != links.internal || links.internal == "" {
+			return fmt.Errorf("%w: account %s is not user %s's internal account", ErrAccountNotOwned, debitedAccountId, userId)
+		}
+		internal, err := isInternalAccount(ctx, tx, creditedAccountId)
+		if err != nil {
+			return err
+		}
+		if !internal {
+			return fmt.Errorf("%w: transfers can only credit internal accounts, %s is not one", ErrInvalidPosting, creditedAccountId)
+		}
+	default:
+		return fmt.Errorf("%w: unknown posting kind %q", ErrInvalidRequest, kind)
+	}
+	return nil
+}
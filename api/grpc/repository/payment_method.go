@@ -0,0 +1,217 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
+)
+
+// Payment method types
+const (
+	// PaymentMethodACH is a bank account, identified by its account and routing numbers
+	PaymentMethodACH = "ACH"
+	// PaymentMethodCard is a debit or credit card, identified by its number and expiration date
+	PaymentMethodCard = "card"
+)
+
+// PaymentMethod is a bank account or card a user moves money in and out of the ledger with.
+// A payment method must be verified before it can be used.
+type PaymentMethod struct {
+	Id         string
+	UserId     string
+	MethodType string
+	// AccountNumber and RoutingNumber are only set for ACH payment methods
+	AccountNumber string
+	RoutingNumber string
+	// CardNumber and ExpirationDate are only set for cards. ExpirationDate is the first day
+	// of the month the card expires in.
+	CardNumber     string
+	ExpirationDate *time.Time
+	IsVerified     bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	RemovedAt      *time.Time
+}
+
+// LastFour returns the last four digits of the account or card number, which identify a
+// payment method to its user without revealing the whole number
+func (p *PaymentMethod) LastFour() string {
+	number := p.AccountNumber
+	if p.MethodType == PaymentMethodCard {
+		number = p.CardNumber
+	}
+	if len(number) <= 4 {
+		return number
+	}
+	return number[len(number)-4:]
+}
+
+type PaymentMethodRepository struct {
+	db *sql.DB
+	ID identifier.ID
+}
+
+func NewPaymentMethodRepository(db *sql.DB, prefix string) *PaymentMethodRepository {
+	return &PaymentMethodRepository{db: db, ID: identifier.ID(prefix)}
+}
+
+// paymentMethodColumns lists the columns of a PaymentMethod, in the order
+// scanPaymentMethod scans them
+const paymentMethodColumns = `id, user_id, method_type, COALESCE(account_number, ''), COALESCE(routing_number, ''),
+	COALESCE(card_number, ''), expiration_date, is_verified, created_at, updated_at, removed_at`
+
+const selectPaymentMethods = "SELECT " + paymentMethodColumns + " FROM payment_methods"
+
+func scanPaymentMethod(row rowScanner) (*PaymentMethod, error) {
+	var method PaymentMethod
+	var expirationDate, removedAt sql.NullTime
+	err := row.Scan(&method.Id, &method.UserId, &method.MethodType, &method.AccountNumber, &method.RoutingNumber,
+		&method.CardNumber, &expirationDate, &method.IsVerified, &method.CreatedAt, &method.UpdatedAt, &removedAt)
+	if err != nil {
+		return nil, err
+	}
+	method.ExpirationDate = nullTime(expirationDate)
+	method.RemovedAt = nullTime(removedAt)
+	return &method, nil
+}
+
+// AddPaymentMethod adds an unverified payment method for a user, who must exist and be active
+func (p *PaymentMethodRepository) AddPaymentMethod(ctx context.Context, method *PaymentMethod) (*PaymentMethod, error) {
+	var added *PaymentMethod
+	err := runInTx(ctx, p.db, nil, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		if err := checkAccountOwner(ctx, tx, method.UserId); err != nil {
+			return err
+		}
+
+		var expirationDate sql.NullTime
+		if method.ExpirationDate != nil {
+			expirationDate = sql.NullTime{Time: *method.ExpirationDate, Valid: true}
+		}
+		var err error
+		added, err = scanPaymentMethod(tx.QueryRowContext(ctx, `
+			INSERT INTO payment_methods (id, user_id, method_type, account_number, routing_number, card_number, expiration_date,
+				is_verified, created_by, updated_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, FALSE, $2, $2)
+			RETURNING `+paymentMethodColumns,
+			string(p.ID.New()), method.UserId, method.MethodType, nullString(method.AccountNumber), nullString(method.RoutingNumber),
+			nullString(method.CardNumber), expirationDate))
+		return err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error while adding payment method", "error", err)
+		return nil, err
+	}
+	return added, nil
+}
+
+// ListPaymentMethods lists a user's payment methods that have not been removed, oldest first
+func (p *PaymentMethodRepository) ListPaymentMethods(ctx context.Context, userId string) ([]PaymentMethod, error) {
+	rows, err := p.db.QueryContext(ctx, selectPaymentMethods+" WHERE user_id = $1 AND removed_at IS NULL ORDER BY created_at, id", userId)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while listing payment methods", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var methods []PaymentMethod
+	for rows.Next() {
+		method, err := scanPaymentMethod(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning payment method: %w", err)
+		}
+		methods = append(methods, *method)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating payment methods: %w", err)
+	}
+	return methods, nil
+}
+
+// VerifyPaymentMethod marks a user's payment method as verified, after which money can be
+// moved with it. Verifying a payment method twice is a no-op.
+//
+// note in this mvp, the caller is trusted to have verified the payment method, e.g. with
+// micro-deposits; in the next iteration the verification itself would live here
+func (p *PaymentMethodRepository) VerifyPaymentMethod(ctx context.Context, userId, paymentMethodId string) (*PaymentMethod, error) {
+	return p.updatePaymentMethod(ctx, userId, paymentMethodId, "verifying", func(tx *sql.Tx, method *PaymentMethod) (*PaymentMethod, error) {
+		if method.IsVerified {
+			return method, nil
+		}
+		return scanPaymentMethod(tx.QueryRowContext(ctx, `
+			UPDATE payment_methods SET is_verified = TRUE, updated_by = $2
+			WHERE id = $1
+			RETURNING `+paymentMethodColumns, paymentMethodId, userId))
+	})
+}
+
+// RemovePaymentMethod removes a user's payment method, so it can no longer be used. The
+// transactions made with it keep referring to it.
+func (p *PaymentMethodRepository) RemovePaymentMethod(ctx context.Context, userId, paymentMethodId string) (*PaymentMethod, error) {
+	return p.updatePaymentMethod(ctx, userId, paymentMethodId, "removing", func(tx *sql.Tx, method *PaymentMethod) (*PaymentMethod, error) {
+		return scanPaymentMethod(tx.QueryRowContext(ctx, `
+			UPDATE payment_methods SET removed_at = CURRENT_TIMESTAMP, updated_by = $2
+			WHERE id = $1
+			RETURNING `+paymentMethodColumns, paymentMethodId, userId))
+	})
+}
+
+// updatePaymentMethod locks a user's payment method and applies an update to it
+func (p *PaymentMethodRepository) updatePaymentMethod(ctx context.Context, userId, paymentMethodId, action string,
+	update func(tx *sql.Tx, method *PaymentMethod) (*PaymentMethod, error)) (*PaymentMethod, error) {
+	var updated *PaymentMethod
+	err := runInTx(ctx, p.db, nil, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		method, err := readOwnedPaymentMethod(ctx, tx, userId, paymentMethodId, "FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		updated, err = update(tx, method)
+		return err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error while "+action+" payment method", "payment_method_id", paymentMethodId, "error", err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// readOwnedPaymentMethod reads a payment method of the user that has not been removed,
+// locking it with lock
+func readOwnedPaymentMethod(ctx context.Context, q Querier, userId, paymentMethodId, lock string) (*PaymentMethod, error) {
+	method, err := scanPaymentMethod(q.QueryRowContext(ctx, selectPaymentMethods+" WHERE id = $1 "+lock, paymentMethodId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrPaymentMethodNotFound, paymentMethodId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading payment method %s: %w", paymentMethodId, err)
+	}
+	if method.RemovedAt != nil {
+		return nil, fmt.Errorf("%w: %s was removed", ErrPaymentMethodNotFound, paymentMethodId)
+	}
+	if method.UserId != userId {
+		return nil, fmt.Errorf("%w: payment method %s is not user %s's", ErrPaymentMethodNotOwned, paymentMethodId, userId)
+	}
+	return method, nil
+}
+
+// checkPaymentMethod checks that a user can move money with a payment method: it must be
+// theirs, verified and not removed. The payment method is locked against being removed for
+// the rest of the transaction.
+func checkPaymentMethod(ctx context.Context, tx *sql.Tx, userId, paymentMethodId string) error {
+	method, err := readOwnedPaymentMethod(ctx, tx, userId, paymentMethodId, "FOR SHARE")
+	if err != nil {
+		return err
+	}
+	if !method.IsVerified {
+		return fmt.Errorf("%w: %s", ErrPaymentMethodNotVerified, paymentMethodId)
+	}
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestPaymentMethodRepository(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_deposit_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	transactions := NewTransactionRepository(db, "txn_", "le_")
+	methods := NewPaymentMethodRepository(db, "pm_")
+
+	bank, err := methods.AddPaymentMethod(ctx, &PaymentMethod{UserId: "usr_1", MethodType: PaymentMethodACH, AccountNumber: "000123456789", RoutingNumber: "011000015"})
+	assert.NoError(t, err)
+	assert.False(t, bank.IsVerified)
+	assert.Equal(t, "6789", bank.LastFour())
+	expiration := time.Date(time.Now().Year()+1, time.March, 1, 0, 0, 0, 0, time.UTC)
+	card, err := methods.AddPaymentMethod(ctx, &PaymentMethod{UserId: "usr_1", MethodType: PaymentMethodCard, CardNumber: "4111111111111111", ExpirationDate: &expiration})
+	assert.NoError(t, err)
+	if assert.NotNil(t, card.ExpirationDate) {
+		assert.Equal(t, time.March, card.ExpirationDate.Month())
+	}
+	other, err := methods.AddPaymentMethod(ctx, &PaymentMethod{UserId: "usr_2", MethodType: PaymentMethodACH, AccountNumber: "55555555", RoutingNumber: "011000015"})
+	assert.NoError(t, err)
+	_, err = methods.AddPaymentMethod(ctx, &PaymentMethod{UserId: "usr_9", MethodType: PaymentMethodACH, AccountNumber: "55555555", RoutingNumber: "011000015"})
+	assert.True(t, errors.Is(err, ErrUserNotFound))
+
+	t.Run("an unverified payment method cannot be used", func(t *testing.T) {
+		_, err := transactions.DepositFunds(ctx, usd(100), "usr_1", "acct_2", "acct_1", bank.Id)
+		assert.True(t, errors.Is(err, ErrPaymentMethodNotVerified))
+	})
+
+	t.Run("only the owner can verify a payment method", func(t *testing.T) {
+		_, err := methods.VerifyPaymentMethod(ctx, "usr_2", bank.Id)
+		assert.True(t, errors.Is(err, ErrPaymentMethodNotOwned))
+
+		verified, err := methods.VerifyPaymentMethod(ctx, "usr_1", bank.Id)
+		assert.NoError(t, err)
+		assert.True(t, verified.IsVerified)
+		// Verifying again changes nothing
+		again, err := methods.VerifyPaymentMethod(ctx, "usr_1", bank.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, verified.UpdatedAt, again.UpdatedAt)
+	})
+
+	t.Run("deposits and withdrawals record the payment method", func(t *testing.T) {
+		depositId, err := transactions.DepositFunds(ctx, usd(100), "usr_1", "acct_2", "acct_1", bank.Id)
+		assert.NoError(t, err)
+		deposit, err := transactions.GetTransaction(ctx, depositId)
+		assert.NoError(t, err)
+		assert.Equal(t, bank.Id, deposit.PaymentMethodId)
+
+		withdrawalId, err := transactions.WithdrawFunds(ctx, usd(40), "usr_1", "acct_1", "acct_2", bank.Id)
+		assert.NoError(t, err)
+		withdrawal, err := transactions.GetTransaction(ctx, withdrawalId)
+		assert.NoError(t, err)
+		assert.Equal(t, bank.Id, withdrawal.PaymentMethodId)
+
+		// Without a payment method nothing is recorded
+		plainId, err := transactions.DepositFunds(ctx, usd(10), "usr_1", "acct_2", "acct_1", "")
+		assert.NoError(t, err)
+		plain, err := transactions.GetTransaction(ctx, plainId)
+		assert.NoError(t, err)
+		assert.Empty(t, plain.PaymentMethodId)
+	})
+
+	t.Run("another user's payment method cannot be used", func(t *testing.T) {
+		_, err := methods.VerifyPaymentMethod(ctx, "usr_2", other.Id)
+		assert.NoError(t, err)
+		_, err = transactions.DepositFunds(ctx, usd(100), "usr_1", "acct_2", "acct_1", other.Id)
+		assert.True(t, errors.Is(err, ErrPaymentMethodNotOwned))
+		_, err = transactions.DepositFunds(ctx, usd(100), "usr_1", "acct_2", "acct_1", "pm_missing")
+		assert.True(t, errors.Is(err, ErrPaymentMethodNotFound))
+	})
+
+	t.Run("a removed payment method is no longer listed or usable", func(t *testing.T) {
+		listed, err := methods.ListPaymentMethods(ctx, "usr_1")
+		assert.NoError(t, err)
+		assert.Len(t, listed, 2)
+
+		removed, err := methods.RemovePaymentMethod(ctx, "usr_1", bank.Id)
+		assert.NoError(t, err)
+		assert.NotNil(t, removed.RemovedAt)
+		_, err = methods.RemovePaymentMethod(ctx, "usr_1", bank.Id)
+		assert.True(t, errors.Is(err, ErrPaymentMethodNotFound))
+
+		listed, err = methods.ListPaymentMethods(ctx, "usr_1")
+		assert.NoError(t, err)
+		if assert.Len(t, listed, 1) {
+			assert.Equal(t, card.Id, listed[0].Id)
+		}
+		_, err = transactions.DepositFunds(ctx, usd(100), "usr_1", "acct_2", "acct_1", bank.Id)
+		assert.True(t, errors.Is(err, ErrPaymentMethodNotFound))
+	})
+}
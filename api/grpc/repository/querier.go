@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Querier runs queries either directly against the database or inside a transaction. It
+// is satisfied by both *sql.DB and *sql.Tx, so repository methods that accept one can take
+// part in a transaction shared with other repositories.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var (
+	_ Querier = (*sql.DB)(nil)
+	_ Querier = (*sql.Tx)(nil)
+)
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/currency"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+)
+
+// reversibleTransaction is a transaction locked for reversal
+type reversibleTransaction struct {
+	id             string
+	amount         money.Money
+	reversedUnits  int64
+	status         string
+	reversesTxnId  sql.NullString
+	legs           []ledgerLeg
+	remainingUnits int64
+}
+
+// isDoubleEntry reports whether the transaction is a single debit and credit in one
+// currency, as opposed to a multi-leg journal entry
+func (r *reversibleTransaction) isDoubleEntry() bool {
+	return len(r.legs) == 2 && r.amount.Currency != ""
+}
+
+// ReverseTransaction posts a new transaction whose ledger entries mirror those of the
+// original, moving amount back from the credited to the debited account. A nil amount
+// reverses whatever has not been reversed yet. The original's ledger entries are never
+// modified; only its status and reversed amount are updated.
+//
+// A reversal is not checked for sufficient balance on the account it debits: undoing a
+// posting must always be possible, even if the credited account has since spent the funds.
+func (t *TransactionRepository) ReverseTransaction(ctx context.Context, originalTxnId string, amount *money.Money, userId string) (string, error) {
+	var txnId string
+	err := t.runInTx(ctx, func(tx *sql.Tx) error {
+		original, err := t.lockReversibleTransaction(ctx, tx, originalTxnId)
+		if err != nil {
+			return err
+		}
+
+		units := original.remainingUnits
+		if amount != nil {
+			if !original.isDoubleEntry() {
+				return fmt.Errorf("%w: journal entry %s can only be reversed in full", ErrNotReversible, originalTxnId)
+			}
+			if amount.Currency != original.amount.Currency {
+				return fmt.Errorf("%w: reversal is in %s but transaction %s is in %s",
+					money.ErrCurrencyMismatch, amount.Currency, originalTxnId, original.amount.Currency)
+			}
+			if amount.Units <= 0 || amount.Units > original.remainingUnits {
+				return fmt.Errorf("%w: reversal amount %s must be greater than zero and at most the %s not yet reversed", money.ErrInvalidAmount,
+					amount, money.Money{Units: original.remainingUnits, Currency: original.amount.Currency})
+			}
+			units = amount.Units
+		}
+
+		// Every leg of the original is mirrored: debits become credits and vice versa. Only a
+		// simple double entry can be partially reversed, and both of its legs are for the
+		// transaction amount, so they are mirrored for the reversed amount.
+		legs := make([]ledgerLeg, 0, len(original.legs))
+		for _, leg := range original.legs {
+			mirror := leg
+			mirror.direction = "debit"
+			if leg.direction == "debit" {
+				mirror.direction = "credit"
+			}
+			if original.isDoubleEntry() {
+				mirror.units = units
+			}
+			legs = append(legs, mirror)
+		}
+
+		if err := checkLegStates(ctx, tx, legs); err != nil {
+			return err
+		}
+		changes, err := legBalanceChanges(ctx, tx, legs)
+		if err != nil {
+			return err
+		}
+
+		txnId, err = t.insertTransaction(ctx, tx, money.Money{Units: units, Currency: original.amount.Currency}, legs, userId, originalTxnId, "")
+		if err != nil {
+			return err
+		}
+		if err := applyBalanceChanges(ctx, tx, changes...); err != nil {
+			return err
+		}
+
+		status := TransactionStatusPartiallyReversed
+		if original.reversedUnits+units == original.amount.Units {
+			status = TransactionStatusReversed
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE transactions SET reversed_amount = reversed_amount + $2, status = $3, updated_by = $4 WHERE id = $1",
+			originalTxnId, units, status, userId)
+		if err != nil {
+			slog.ErrorContext(ctx, "error while marking transaction reversed", "error", err)
+			return err
+		}
+		return recordEvent(ctx, tx, EventTransactionReversed, originalTxnId, transactionReversedEvent{
+			TransactionId:  originalTxnId,
+			ReversalId:     txnId,
+			Amount:         units,
+			ReversedAmount: original.reversedUnits + units,
+			Currency:       original.amount.Currency,
+			Status:         status,
+			ReversedBy:     userId,
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return txnId, nil
+}
+
+// lockReversibleTransaction reads a transaction and its ledger entries and locks it until
+// the end of the transaction, failing if it cannot be reversed any further
+func (t *TransactionRepository) lockReversibleTransaction(ctx context.Context, tx *sql.Tx, txnId string) (*reversibleTransaction, error) {
+	original := &reversibleTransaction{id: txnId}
+	var code sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		SELECT amount, currency, reversed_amount, status, reverses_transaction_id
+		FROM transactions
+		WHERE id = $1
+		FOR UPDATE
+	`, txnId).Scan(&original.amount.Units, &code, &original.reversedUnits, &original.status, &original.reversesTxnId)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrTransactionNotFound, txnId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	original.amount.Currency = code.String
+
+	if original.reversesTxnId.Valid {
+		return nil, fmt.Errorf("%w: transaction %s is a reversal of %s", ErrNotReversible, txnId, original.reversesTxnId.String)
+	}
+	if original.status == TransactionStatusReversed {
+		return nil, fmt.Errorf("%w: transaction %s is already reversed", ErrNotReversible, txnId)
+	}
+	original.remainingUnits = original.amount.Units - original.reversedUnits
+
+	rows, err := tx.QueryContext(ctx, "SELECT account_id, direction, amount, currency FROM ledger_entries WHERE transaction_id = $1 ORDER BY id", txnId)
+	if err != nil {
+		return nil, fmt.Errorf("error querying ledger entries: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var leg ledgerLeg
+		var legCurrency string
+		if err := rows.Scan(&leg.accountId, &leg.direction, &leg.units, &legCurrency); err != nil {
+			return nil, fmt.Errorf("error scanning ledger entry: %w", err)
+		}
+		if leg.currency, err = currency.Lookup(legCurrency); err != nil {
+			return nil, err
+		}
+		original.legs = append(original.legs, leg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ledger entries: %w", err)
+	}
+	if len(original.legs) == 0 {
+		return nil, fmt.Errorf("transaction %s has no ledger entries", txnId)
+	}
+
+	return original, nil
+}
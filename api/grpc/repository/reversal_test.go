@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestTransactionRepository_ReverseTransaction(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_withdraw_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	accounts := NewAccountRepository(db, "acct_")
+	repo := NewTransactionRepository(db, "txn_", "le_")
+
+	assertPosted := func(accountId string, posted int64) {
+		t.Helper()
+		balance, err := accounts.GetAccountBalance(ctx, accountId, time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, posted, balance.Amount.Units)
+	}
+	amount := func(units int64) *money.Money {
+		m := usd(units)
+		return &m
+	}
+
+	// txn_1 credited acct_1 with 100 from acct_2; acct_1 starts with 150 and acct_2 with -150
+	var partialId string
+	tests := []struct {
+		name       string
+		txnId      string
+		amount     *money.Money
+		wantStatus string
+		wantPosted int64
+		wantErr    bool
+	}{
+		{
+			name:    "transaction does not exist",
+			txnId:   "txn_404",
+			wantErr: true,
+		},
+		{
+			name:    "more than the original amount",
+			txnId:   "txn_1",
+			amount:  amount(101),
+			wantErr: true,
+		},
+		{
+			name:    "currency does not match",
+			txnId:   "txn_1",
+			amount:  &money.Money{Units: 10, Currency: "EUR"},
+			wantErr: true,
+		},
+		{
+			name:       "partial reversal",
+			txnId:      "txn_1",
+			amount:     amount(40),
+			wantStatus: TransactionStatusPartiallyReversed,
+			wantPosted: 110,
+		},
+		{
+			name:    "more than the remaining amount",
+			txnId:   "txn_1",
+			amount:  amount(61),
+			wantErr: true,
+		},
+		{
+			name:       "reverse the remainder",
+			txnId:      "txn_1",
+			wantStatus: TransactionStatusReversed,
+			wantPosted: 50,
+		},
+		{
+			name:    "already fully reversed",
+			txnId:   "txn_1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reversalId, err := repo.ReverseTransaction(ctx, tt.txnId, tt.amount, "usr_1")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, identifier.ID(reversalId).Validate())
+			if partialId == "" {
+				partialId = reversalId
+			}
+
+			var status string
+			err = db.QueryRow("SELECT status FROM transactions WHERE id = $1", tt.txnId).Scan(&status)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatus, status)
+			assertPosted("acct_1", tt.wantPosted)
+			assertPosted("acct_2", -tt.wantPosted)
+		})
+	}
+
+	t.Run("a reversal cannot be reversed", func(t *testing.T) {
+		_, err := repo.ReverseTransaction(ctx, partialId, nil, "usr_1")
+		assert.Error(t, err)
+	})
+
+	t.Run("reversals are linked in the listing", func(t *testing.T) {
+		accountId := "acct_1"
+		txns, _, err := repo.ListTransactions(ctx, &TransactionFilter{AccountID: &accountId})
+		assert.NoError(t, err)
+		var linked int
+		for _, txn := range txns {
+			if txn.Id == "txn_1" {
+				assert.Equal(t, int64(100), txn.ReversedAmount.Units)
+			}
+			if txn.ReversesTransactionId == "txn_1" {
+				linked++
+			}
+		}
+		assert.Equal(t, 2, linked)
+	})
+
+	t.Run("the original records its reversals and history", func(t *testing.T) {
+		txn, err := repo.GetTransaction(ctx, "txn_1")
+		assert.NoError(t, err)
+		assert.Equal(t, usd(100), txn.Amount)
+		assert.Equal(t, usd(100), txn.ReversedAmount)
+		assert.Equal(t, TransactionStatusReversed, txn.Status)
+		assert.Equal(t, "usr_1", txn.UpdatedBy)
+		assert.Len(t, txn.Legs, 2)
+		assert.Len(t, txn.ReversalIds, 2)
+		if assert.Len(t, txn.History, 2) {
+			assert.Equal(t, TransactionStatusSuccess, txn.History[0].Status)
+			assert.Equal(t, usd(0), txn.History[0].ReversedAmount)
+			assert.Equal(t, TransactionStatusPartiallyReversed, txn.History[1].Status)
+			assert.Equal(t, usd(40), txn.History[1].ReversedAmount)
+			assert.Equal(t, "usr_1", txn.History[1].UpdatedBy)
+		}
+
+		reversal, err := repo.GetTransaction(ctx, txn.ReversalIds[0])
+		assert.NoError(t, err)
+		assert.Equal(t, "txn_1", reversal.ReversesTransactionId)
+		assert.Equal(t, usd(40), reversal.Amount)
+		assert.Equal(t, "usr_1", reversal.CreatedBy)
+		assert.Empty(t, reversal.History)
+	})
+
+	t.Run("a missing transaction is not found", func(t *testing.T) {
+		_, err := repo.GetTransaction(ctx, "txn_404")
+		assert.ErrorIs(t, err, ErrTransactionNotFound)
+	})
+}
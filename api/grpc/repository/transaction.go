@@ -3,228 +3,688 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
+	"github.com/lib/pq"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/currency"
 	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
 )
 
 type TransactionRepository struct {
 	db       *sql.DB
 	txnID    identifier.ID
 	ledgerID identifier.ID
+	retry    RetryPolicy
 }
 
+const (
+	TransactionStatusSuccess           = "success"
+	TransactionStatusReversed          = "reversed"
+	TransactionStatusPartiallyReversed = "partially_reversed"
+)
+
 type Transaction struct {
-	Id        string
+	Id string
+	// AccountId, Direction and Amount describe the transaction from the listed account's
+	// side: how much moved in or out of it, net of all its legs
 	AccountId string
 	Direction string
-	Amount    int64
+	Amount    money.Money
 	Status    string
-	CreatedAt string
-	// Direction string
+	CreatedAt time.Time
+	// CounterpartyAccountId is the other account money moved to or from. It is empty when
+	// the transaction moved money between more than two accounts.
+	CounterpartyAccountId string
+	// Legs are every ledger entry of the transaction, read by GetTransaction and, when the
+	// filter asks for them, ListTransactions
+	Legs      []LedgerEntry
+	CreatedBy string
+	UpdatedAt time.Time
+	UpdatedBy string
+	// ReversalIds and History are only read by GetTransaction. ReversalIds are the reversals
+	// of the transaction and History its previous versions, both oldest first.
+	ReversalIds []string
+	History     []TransactionVersion
+	// ReversesTransactionId links a reversal to the transaction it reverses
+	ReversesTransactionId string
+	// ReversedAmount is how much of this transaction has been reversed so far
+	ReversedAmount money.Money
+	// PaymentMethodId is the payment method a deposit or withdrawal was made with, if any
+	PaymentMethodId string
+}
+
+// TransactionVersion is a previous version of a transaction, recorded by the history trigger
+// when it was updated: the status and reversed amount it had from UpdatedAt on
+type TransactionVersion struct {
+	Status         string
+	ReversedAmount money.Money
+	UpdatedAt      time.Time
+	UpdatedBy      string
+}
+
+// LedgerEntry is one leg of a transaction: a debit or credit of a single account
+type LedgerEntry struct {
+	Id        string
+	AccountId string
+	Direction string
+	Amount    money.Money
 }
 
+// TransactionFilter narrows the transactions listed by ListTransactions. AccountID is
+// required; other nil fields match every transaction.
 type TransactionFilter struct {
 	AccountID *string
-	Cursor    *string
-	Limit     *int
+	// Position is where the page continues from: the page lists the transactions after it,
+	// or with Backward, the transactions before it
+	Position *PagePosition
+	Backward bool
+	Limit    *int
+	// CreatedFrom and CreatedTo bound when the transaction was posted, inclusive of
+	// CreatedFrom and exclusive of CreatedTo
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	// Direction is "debit" or "credit" from the listed account's point of view
+	Direction *string
+	Status    *string
+	// MinAmount and MaxAmount bound, inclusively, the amount that moved in or out of the
+	// listed account
+	MinAmount *money.Money
+	MaxAmount *money.Money
+	// CounterpartyAccountID matches transactions that also moved money in or out of this account
+	CounterpartyAccountID *string
+	CreatedBy             *string
+	// TransactionIDs matches only the transactions with these IDs
+	TransactionIDs []string
+	// Descending lists the newest transactions first
+	Descending bool
+	// IncludeLegs reads every ledger entry of the listed transactions
+	IncludeLegs bool
 }
 
-// TODO thing about how to elegantly handle storing amount as bigint without needing to amount*100 everywhere
+// DefaultTransactionPageSize is the number of transactions listed when no limit is given
+const DefaultTransactionPageSize = 50
+
+// PagePosition is the sort key of a listed transaction. Transactions are listed by when
+// they were posted, with the ID breaking ties between transactions posted together.
+type PagePosition struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Page holds the positions that the pages either side of a listed page continue from
+type Page struct {
+	// Next is nil on the last page
+	Next *PagePosition
+	// Prev is nil on the first page
+	Prev *PagePosition
+}
 
 func NewTransactionRepository(db *sql.DB, txnPrefix, ledgerPrefix string) *TransactionRepository {
-	return &TransactionRepository{db: db, txnID: identifier.ID(txnPrefix), ledgerID: identifier.ID(ledgerPrefix)}
+	return &TransactionRepository{db: db, txnID: identifier.ID(txnPrefix), ledgerID: identifier.ID(ledgerPrefix), retry: DefaultRetryPolicy}
 }
 
-func (t *TransactionRepository) ListTransactions(ctx context.Context, filter *TransactionFilter) ([]Transaction, string, error) {
+// ListTransactions lists a page of the transactions matching the filter, oldest first
+// unless the filter is descending
+func (t *TransactionRepository) ListTransactions(ctx context.Context, filter *TransactionFilter) ([]Transaction, Page, error) {
+	if filter.AccountID == nil || *filter.AccountID == "" {
+		return nil, Page{}, fmt.Errorf("%w: an account is required to list transactions", ErrInvalidRequest)
+	}
+
+	// le is the listed account's side of each transaction. Its legs are summed, so a
+	// transaction that debits and credits the account more than once is still listed once.
 	query := `
-	SELECT DISTINCT t.id, le.account_id, t.amount, t.status, le.direction, t.created_at
-	FROM transactions t
-	JOIN ledger_entries le ON t.id = le.transaction_id
+	SELECT t.id, le.account_id, le.amount, le.currency, t.status, le.direction, t.created_at,
+		t.created_by, t.updated_at, t.updated_by, COALESCE(t.reverses_transaction_id, ''), t.reversed_amount,
+		COALESCE(t.external_payment_method_id, ''),
+		(
+			SELECT CASE WHEN COUNT(DISTINCT cp.account_id) = 1 THEN MIN(cp.account_id) END
+			FROM ledger_entries cp
+			WHERE cp.transaction_id = t.id AND cp.account_id <> le.account_id
+		)
+	FROM (
+		SELECT transaction_id, account_id, currency,
+			ABS(SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END)) AS amount,
+			CASE WHEN SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END) >= 0
+				THEN 'credit' ELSE 'debit' END AS direction
+		FROM ledger_entries
+		WHERE account_id = $1
+		GROUP BY transaction_id, account_id, currency
+	) le
+	JOIN transactions t ON t.id = le.transaction_id
 	WHERE 1=1
 `
 
-	var args []interface{}
+	args := []interface{}{*filter.AccountID}
 	var conditions []string
-
-	if filter.AccountID != nil && *filter.AccountID != "" {
-		conditions = append(conditions, "le.account_id = $"+fmt.Sprint(len(args)+1))
-		args = append(args, *filter.AccountID)
+	addCondition := func(condition string, conditionArgs ...interface{}) {
+		for _, arg := range conditionArgs {
+			args = append(args, arg)
+			condition = strings.Replace(condition, "?", "$"+fmt.Sprint(len(args)), 1)
+		}
+		conditions = append(conditions, condition)
 	}
 
-	if filter.Cursor != nil && *filter.Cursor != "" {
-		conditions = append(conditions, "t.id > $"+fmt.Sprint(len(args)+1))
-		args = append(args, *filter.Cursor)
+	// A backward page is read in reverse from its position and flipped back afterwards
+	ascending := filter.Descending == filter.Backward
+
+	if filter.Position != nil {
+		if ascending {
+			addCondition("(t.created_at, t.id) > (?, ?)", filter.Position.CreatedAt, filter.Position.ID)
+		} else {
+			addCondition("(t.created_at, t.id) < (?, ?)", filter.Position.CreatedAt, filter.Position.ID)
+		}
+	}
+	if filter.CreatedFrom != nil {
+		addCondition("t.created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		addCondition("t.created_at < ?", *filter.CreatedTo)
+	}
+	if filter.Direction != nil && *filter.Direction != "" {
+		addCondition("le.direction = ?", *filter.Direction)
+	}
+	if filter.Status != nil && *filter.Status != "" {
+		addCondition("t.status = ?", *filter.Status)
+	}
+	if filter.MinAmount != nil {
+		addCondition("le.amount >= ?", filter.MinAmount.Units)
+		addCondition("le.currency = ?", filter.MinAmount.Currency)
+	}
+	if filter.MaxAmount != nil {
+		addCondition("le.amount <= ?", filter.MaxAmount.Units)
+		addCondition("le.currency = ?", filter.MaxAmount.Currency)
+	}
+	if filter.CounterpartyAccountID != nil && *filter.CounterpartyAccountID != "" {
+		addCondition(`EXISTS (
+			SELECT 1 FROM ledger_entries cp
+			WHERE cp.transaction_id = t.id AND cp.account_id = ? AND cp.account_id <> le.account_id
+		)`, *filter.CounterpartyAccountID)
+	}
+	if filter.CreatedBy != nil && *filter.CreatedBy != "" {
+		addCondition("t.created_by = ?", *filter.CreatedBy)
+	}
+	if len(filter.TransactionIDs) > 0 {
+		addCondition("t.id = ANY(?)", pq.Array(filter.TransactionIDs))
 	}
 
 	if len(conditions) > 0 {
 		query += " AND " + strings.Join(conditions, " AND ")
 	}
 
-	query += " ORDER BY t.id"
+	if ascending {
+		query += " ORDER BY t.created_at, t.id"
+	} else {
+		query += " ORDER BY t.created_at DESC, t.id DESC"
+	}
 
-	if filter.Limit != nil {
-		query += " LIMIT $" + fmt.Sprint(len(args)+1)
-		args = append(args, *filter.Limit)
+	limit := DefaultTransactionPageSize
+	if filter.Limit != nil && *filter.Limit > 0 {
+		limit = *filter.Limit
 	}
+	// One extra row tells whether there is another page
+	query += " LIMIT $" + fmt.Sprint(len(args)+1)
+	args = append(args, limit+1)
 
-	rows, err := t.db.Query(query, args...)
+	rows, err := t.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, "", fmt.Errorf("error querying transactions: %v", err)
+		return nil, Page{}, fmt.Errorf("error querying transactions: %v", err)
 	}
 	defer rows.Close()
 
 	var transactions []Transaction
-	var lastID string
 
 	for rows.Next() {
 		var txn Transaction
+		var counterparty sql.NullString
 
-		err := rows.Scan(&txn.Id, &txn.AccountId, &txn.Amount, &txn.Status, &txn.Direction, &txn.CreatedAt)
+		err := rows.Scan(&txn.Id, &txn.AccountId, &txn.Amount.Units, &txn.Amount.Currency, &txn.Status, &txn.Direction, &txn.CreatedAt,
+			&txn.CreatedBy, &txn.UpdatedAt, &txn.UpdatedBy, &txn.ReversesTransactionId, &txn.ReversedAmount.Units, &txn.PaymentMethodId, &counterparty)
 
 		if err != nil {
-			return nil, "", fmt.Errorf("error scanning transaction: %v", err)
+			return nil, Page{}, fmt.Errorf("error scanning transaction: %v", err)
 		}
-		txn.AccountId = *filter.AccountID
-		txn.Amount = txn.Amount / 100
+		txn.CounterpartyAccountId = counterparty.String
+		txn.ReversedAmount.Currency = txn.Amount.Currency
 		transactions = append(transactions, txn)
-		lastID = txn.Id
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, "", fmt.Errorf("error iterating transactions: %v", err)
+		return nil, Page{}, fmt.Errorf("error iterating transactions: %v", err)
 	}
 
-	var nextCursor string
-	if filter.Limit != nil && len(transactions) == *filter.Limit {
-		nextCursor = lastID
+	more := len(transactions) > limit
+	if more {
+		transactions = transactions[:limit]
+	}
+	if filter.Backward {
+		for i, j := 0, len(transactions)-1; i < j; i, j = i+1, j-1 {
+			transactions[i], transactions[j] = transactions[j], transactions[i]
+		}
+	}
+	if len(transactions) == 0 {
+		return transactions, Page{}, nil
+	}
+	if filter.IncludeLegs {
+		if err := readLegs(ctx, t.db, transactions); err != nil {
+			return nil, Page{}, err
+		}
 	}
 
-	return transactions, nextCursor, nil
+	// A page read forward from a position has the page it came from before it, and one read
+	// backward has the page it came from after it
+	var page Page
+	first, last := positionOf(transactions[0]), positionOf(transactions[len(transactions)-1])
+	if filter.Backward {
+		page.Next = &last
+		if more {
+			page.Prev = &first
+		}
+	} else {
+		if more {
+			page.Next = &last
+		}
+		if filter.Position != nil {
+			page.Prev = &first
+		}
+	}
+	return transactions, page, nil
 }
 
-// DepositFunds deposits funds into an account
-func (t *TransactionRepository) DepositFunds(ctx context.Context, amount float64, userId, debitAccountId, creditAccountId string) (string, error) {
-	return t.addDoubleEntryTransactionFromExternal(ctx, amount, debitAccountId, creditAccountId, userId)
+func positionOf(txn Transaction) PagePosition {
+	return PagePosition{CreatedAt: txn.CreatedAt, ID: txn.Id}
 }
 
-// WithdrawFunds withdraws funds from an account
-func (t *TransactionRepository) WithdrawFunds(ctx context.Context, amount float64, userId, debitAccountId, creditAccountId string) (string, error) {
-	return t.addDoubleEntryTransaction(ctx, amount, debitAccountId, creditAccountId, userId)
+// GetTransaction reads a transaction with all its legs, its reversals and the history of its
+// status changes. The amount is the transaction's own, not one account's side of it, so
+// AccountId, Direction and CounterpartyAccountId are left empty.
+func (t *TransactionRepository) GetTransaction(ctx context.Context, txnId string) (*Transaction, error) {
+	var txn *Transaction
+	// Read everything from one snapshot, so the reversals add up to the reversed amount
+	opts := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := runInTx(ctx, t.db, opts, t.retry, func(tx *sql.Tx) error {
+		var err error
+		txn, err = readTransaction(ctx, tx, txnId)
+		if err != nil {
+			return err
+		}
+		txn.ReversalIds, err = readReversalIds(ctx, tx, txnId)
+		if err != nil {
+			return err
+		}
+		transactions := []Transaction{*txn}
+		if err := readLegs(ctx, tx, transactions); err != nil {
+			return err
+		}
+		txn.Legs = transactions[0].Legs
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return txn, nil
 }
 
-// TransferFunds transfers funds from one account to another
-func (t *TransactionRepository) TransferFunds(ctx context.Context, amount float64, userId, debitAccountId, creditAccountId string) (string, error) {
-	return t.addDoubleEntryTransaction(ctx, amount, debitAccountId, creditAccountId, userId)
+// transactionVersion is how the history trigger stores a previous version of a transaction
+type transactionVersion struct {
+	Status         string    `json:"status"`
+	ReversedAmount int64     `json:"reversed_amount"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	UpdatedBy      string    `json:"updated_by"`
 }
 
-// addDoubleEntryTransactionFromExternal adds a transaction with a double ledger entry
-func (t *TransactionRepository) addDoubleEntryTransactionFromExternal(ctx context.Context, amount float64, debitedAccountId, creditedAccountId, userId string) (string, error) {
-	tx, err := t.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+func readTransaction(ctx context.Context, q Querier, txnId string) (*Transaction, error) {
+	var txn Transaction
+	var history []byte
+	err := q.QueryRowContext(ctx, `
+		SELECT id, amount, currency, status, created_by, created_at, updated_by, updated_at,
+			COALESCE(reverses_transaction_id, ''), reversed_amount, COALESCE(external_payment_method_id, ''),
+			COALESCE(array_to_json(history), '[]')
+		FROM transactions
+		WHERE id = $1
+	`, txnId).Scan(&txn.Id, &txn.Amount.Units, &txn.Amount.Currency, &txn.Status, &txn.CreatedBy, &txn.CreatedAt,
+		&txn.UpdatedBy, &txn.UpdatedAt, &txn.ReversesTransactionId, &txn.ReversedAmount.Units, &txn.PaymentMethodId, &history)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrTransactionNotFound, txnId)
+	}
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("error reading transaction %s: %w", txnId, err)
 	}
-	defer tx.Rollback()
+	txn.ReversedAmount.Currency = txn.Amount.Currency
 
-	// note in this mvp, we are not checking to see if a user has enough funds in their external account to deposit funds
-	// in the next iteration i would rely on the third party api to determine that
+	var versions []transactionVersion
+	if err := json.Unmarshal(history, &versions); err != nil {
+		return nil, fmt.Errorf("error decoding history of transaction %s: %w", txnId, err)
+	}
+	for _, v := range versions {
+		txn.History = append(txn.History, TransactionVersion{
+			Status:         v.Status,
+			ReversedAmount: money.Money{Units: v.ReversedAmount, Currency: txn.Amount.Currency},
+			UpdatedAt:      v.UpdatedAt,
+			UpdatedBy:      v.UpdatedBy,
+		})
+	}
+	return &txn, nil
+}
 
-	txnId := t.txnID.New()
-	_, err = tx.ExecContext(ctx, "INSERT INTO transactions (id, amount, status, created_by) VALUES ($1, $2, $3, $4)",
-		txnId, amount*100, "success", userId)
+func readReversalIds(ctx context.Context, q Querier, txnId string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id FROM transactions WHERE reverses_transaction_id = $1 ORDER BY created_at, id
+	`, txnId)
 	if err != nil {
-		slog.ErrorContext(ctx, "error while creating transaction", "error", err)
-		return "", err
+		return nil, fmt.Errorf("error querying reversals of transaction %s: %w", txnId, err)
 	}
+	defer rows.Close()
 
-	ledgerId1 := t.ledgerID.New()
-	_, err = tx.ExecContext(ctx, "INSERT INTO ledger_entries (id, transaction_id, account_id, amount, direction, created_by) VALUES ($1, $2, $3, $4, $5, $6)",
-		ledgerId1, txnId, debitedAccountId, amount*100, "debit", userId)
-	if err != nil {
-		slog.ErrorContext(ctx, "error while creating credit ledger entry", "error", err)
-		return "", err
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning reversal: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reversals: %w", err)
+	}
+	return ids, nil
+}
+
+// readLegs reads the ledger entries of a page of transactions in one query
+func readLegs(ctx context.Context, q Querier, transactions []Transaction) error {
+	ids := make([]string, len(transactions))
+	index := make(map[string]int, len(transactions))
+	for i, txn := range transactions {
+		ids[i] = txn.Id
+		index[txn.Id] = i
 	}
 
-	ledgerId2 := t.ledgerID.New()
-	_, err = tx.ExecContext(ctx, "INSERT INTO ledger_entries (id,  transaction_id, account_id, amount, direction, created_by) VALUES ($1, $2, $3, $4, $5, $6)",
-		ledgerId2, txnId, creditedAccountId, amount*100, "credit", userId)
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, transaction_id, account_id, direction, amount, currency
+		FROM ledger_entries
+		WHERE transaction_id = ANY($1)
+		ORDER BY transaction_id, id
+	`, pq.Array(ids))
 	if err != nil {
-		slog.ErrorContext(ctx, "error while creating credit ledger entry", "error", err)
-		return "", err
+		return fmt.Errorf("error querying ledger entries: %w", err)
 	}
+	defer rows.Close()
 
-	if err := tx.Commit(); err != nil {
-		return "", fmt.Errorf("error committing transaction: %w", err)
+	for rows.Next() {
+		var leg LedgerEntry
+		var transactionId string
+		if err := rows.Scan(&leg.Id, &transactionId, &leg.AccountId, &leg.Direction, &leg.Amount.Units, &leg.Amount.Currency); err != nil {
+			return fmt.Errorf("error scanning ledger entry: %w", err)
+		}
+		i := index[transactionId]
+		transactions[i].Legs = append(transactions[i].Legs, leg)
 	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating ledger entries: %w", err)
+	}
+	return nil
+}
 
-	return string(txnId), nil
+// DepositFunds deposits funds from a user's external account into their internal account.
+// paymentMethodId is the user's verified payment method the money comes from, which is
+// recorded on the transaction; it may be empty.
+func (t *TransactionRepository) DepositFunds(ctx context.Context, amount money.Money, userId, debitAccountId, creditAccountId, paymentMethodId string) (string, error) {
+	return t.addDoubleEntryTransactionFromExternal(ctx, amount, debitAccountId, creditAccountId, userId, paymentMethodId)
 }
 
-// addDoubleEntryTransaction adds a transaction with a double ledger entry
-func (t *TransactionRepository) addDoubleEntryTransaction(ctx context.Context, amount float64, debitedAccountId, creditedAccountId, userId string) (string, error) {
-	tx, err := t.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+// WithdrawFunds withdraws funds from a user's internal account to their external account.
+// paymentMethodId is the user's verified payment method the money goes to, which is
+// recorded on the transaction; it may be empty.
+func (t *TransactionRepository) WithdrawFunds(ctx context.Context, amount money.Money, userId, debitAccountId, creditAccountId, paymentMethodId string) (string, error) {
+	return t.addDoubleEntryTransaction(ctx, postingWithdrawal, amount, debitAccountId, creditAccountId, userId, paymentMethodId)
+}
+
+// TransferFunds transfers funds from a user's internal account to another internal account
+func (t *TransactionRepository) TransferFunds(ctx context.Context, amount money.Money, userId, debitAccountId, creditAccountId string) (string, error) {
+	return t.addDoubleEntryTransaction(ctx, postingTransfer, amount, debitAccountId, creditAccountId, userId, "")
+}
+
+// addDoubleEntryTransactionFromExternal adds a transaction with a double ledger entry
+func (t *TransactionRepository) addDoubleEntryTransactionFromExternal(ctx context.Context, amount money.Money, debitedAccountId, creditedAccountId, userId, paymentMethodId string) (string, error) {
+	var txnId string
+	err := t.runInTx(ctx, func(tx *sql.Tx) error {
+		if err := checkLinkedAccounts(ctx, tx, postingDeposit, userId, debitedAccountId, creditedAccountId); err != nil {
+			return err
+		}
+		if paymentMethodId != "" {
+			if err := checkPaymentMethod(ctx, tx, userId, paymentMethodId); err != nil {
+				return err
+			}
+		}
+
+		cur, err := t.postingCurrency(ctx, tx, amount, debitedAccountId, creditedAccountId)
+		if err != nil {
+			slog.ErrorContext(ctx, "error resolving posting currency", "error", err)
+			return err
+		}
+
+		// note in this mvp, we are not checking to see if a user has enough funds in their external account to deposit funds
+		// in the next iteration i would rely on the third party api to determine that
+		debitBalance, err := readAccountBalance(ctx, tx, debitedAccountId)
+		if err != nil {
+			return err
+		}
+		creditBalance, err := readAccountBalance(ctx, tx, creditedAccountId)
+		if err != nil {
+			return err
+		}
+
+		txnId, err = t.insertDoubleEntry(ctx, tx, cur, amount.Units, debitedAccountId, creditedAccountId, userId, paymentMethodId)
+		if err != nil {
+			return err
+		}
+
+		return t.applyPosting(ctx, tx, debitBalance, creditBalance, amount.Units)
+	})
 	if err != nil {
 		return "", err
 	}
-	defer tx.Rollback()
 
-	// Check if the debited account has sufficient balance
-	sufficient, err := t.checkSufficientBalance(ctx, tx, debitedAccountId, amount)
+	return txnId, nil
+}
+
+// addDoubleEntryTransaction adds a transaction with a double ledger entry
+func (t *TransactionRepository) addDoubleEntryTransaction(ctx context.Context, kind postingKind, amount money.Money, debitedAccountId, creditedAccountId, userId, paymentMethodId string) (string, error) {
+	var txnId string
+	err := t.runInTx(ctx, func(tx *sql.Tx) error {
+		if err := checkLinkedAccounts(ctx, tx, kind, userId, debitedAccountId, creditedAccountId); err != nil {
+			return err
+		}
+		if paymentMethodId != "" {
+			if err := checkPaymentMethod(ctx, tx, userId, paymentMethodId); err != nil {
+				return err
+			}
+		}
+
+		// Both legs of a posting must be in the same currency as the amount
+		cur, err := t.postingCurrency(ctx, tx, amount, debitedAccountId, creditedAccountId)
+		if err != nil {
+			slog.ErrorContext(ctx, "error resolving posting currency", "error", err)
+			return err
+		}
+		units := amount.Units
+
+		debitBalance, err := readAccountBalance(ctx, tx, debitedAccountId)
+		if err != nil {
+			slog.Error("error checking balance", "error", err.Error())
+			return fmt.Errorf("error checking balance: %w", err)
+		}
+		creditBalance, err := readAccountBalance(ctx, tx, creditedAccountId)
+		if err != nil {
+			return err
+		}
+
+		// Check if the debited account has sufficient balance
+		if !t.checkSufficientBalance(debitBalance, units) {
+			slog.Error("insufficient balance", "account_id", debitedAccountId)
+			return fmt.Errorf("%w in account %s", ErrInsufficientFunds, debitedAccountId)
+		}
+
+		txnId, err = t.insertDoubleEntry(ctx, tx, cur, units, debitedAccountId, creditedAccountId, userId, paymentMethodId)
+		if err != nil {
+			return err
+		}
+
+		return t.applyPosting(ctx, tx, debitBalance, creditBalance, units)
+	})
 	if err != nil {
-		slog.Error("error checking balance", "error", err.Error())
-		return "", fmt.Errorf("error checking balance: %w", err)
+		return "", err
 	}
-	if !sufficient {
-		slog.Error("insufficient balance", "account_id", debitedAccountId)
-		return "", fmt.Errorf("insufficient balance in account %s", debitedAccountId)
+
+	return txnId, nil
+}
+
+// runInTx runs a money movement in a transaction, retrying it when it loses a race with a
+// concurrent posting. Read committed is sufficient because balances are guarded by the
+// version check on account_balances.
+func (t *TransactionRepository) runInTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return runInTx(ctx, t.db, &sql.TxOptions{Isolation: sql.LevelReadCommitted}, t.retry, fn)
+}
+
+// applyPosting moves units from the debited to the credited account's balance projection.
+// If either balance changed since it was read the posting fails with ErrConcurrentUpdate.
+func (t *TransactionRepository) applyPosting(ctx context.Context, tx *sql.Tx, debitBalance, creditBalance *accountBalance, units int64) error {
+	// Moving money within the same account leaves its balance unchanged
+	if debitBalance.accountId == creditBalance.accountId {
+		return nil
 	}
 
+	return applyBalanceChanges(ctx, tx,
+		balanceChange{balance: debitBalance, postedDelta: -units},
+		balanceChange{balance: creditBalance, postedDelta: units},
+	)
+}
+
+// ledgerLeg is one ledger entry of a posting
+type ledgerLeg struct {
+	accountId string
+	direction string
+	units     int64
+	currency  currency.Currency
+}
+
+// insertDoubleEntry writes the transaction row and its debit and credit ledger entries
+func (t *TransactionRepository) insertDoubleEntry(ctx context.Context, tx *sql.Tx, cur currency.Currency, units int64, debitedAccountId, creditedAccountId, userId, paymentMethodId string) (string, error) {
+	return t.insertTransaction(ctx, tx, money.Money{Units: units, Currency: cur.Code}, []ledgerLeg{
+		{accountId: debitedAccountId, direction: "debit", units: units, currency: cur},
+		{accountId: creditedAccountId, direction: "credit", units: units, currency: cur},
+	}, userId, "", paymentMethodId)
+}
+
+// insertTransaction writes a transaction row, its ledger entries and a transaction.created
+// event. An amount without a currency is stored as a NULL currency, for journal entries that
+// span several currencies. reversesTxnId links a reversal to the transaction it reverses
+// and is empty for every other posting, as is paymentMethodId for everything but deposits
+// and withdrawals made with a payment method.
+func (t *TransactionRepository) insertTransaction(ctx context.Context, tx *sql.Tx, amount money.Money, legs []ledgerLeg, userId, reversesTxnId, paymentMethodId string) (string, error) {
 	txnId := t.txnID.New()
-	_, err = tx.ExecContext(ctx, "INSERT INTO transactions (id, amount, status, created_by) VALUES ($1, $2, $3, $4)",
-		txnId, amount*100, "success", userId)
+	_, err := tx.ExecContext(ctx, "INSERT INTO transactions (id, amount, currency, status, reverses_transaction_id, external_payment_method_id, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		txnId, amount.Units, sql.NullString{String: amount.Currency, Valid: amount.Currency != ""}, TransactionStatusSuccess,
+		sql.NullString{String: reversesTxnId, Valid: reversesTxnId != ""}, nullString(paymentMethodId), userId)
 	if err != nil {
 		slog.ErrorContext(ctx, "error while creating transaction", "error", err)
 		return "", err
 	}
 
-	ledgerId1 := t.ledgerID.New()
-	_, err = tx.ExecContext(ctx, "INSERT INTO ledger_entries (id, transaction_id, account_id, amount, direction, created_by) VALUES ($1, $2, $3, $4, $5, $6)",
-		ledgerId1, txnId, debitedAccountId, amount*100, "debit", userId)
-	if err != nil {
-		slog.ErrorContext(ctx, "error while creating credit ledger entry", "error", err)
-		return "", err
+	event := transactionCreatedEvent{
+		TransactionId:         string(txnId),
+		Amount:                amount.Units,
+		Currency:              amount.Currency,
+		CreatedBy:             userId,
+		ReversesTransactionId: reversesTxnId,
+		PaymentMethodId:       paymentMethodId,
+	}
+	for _, leg := range legs {
+		_, err = tx.ExecContext(ctx, "INSERT INTO ledger_entries (id, transaction_id, account_id, amount, currency, exponent, direction, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			t.ledgerID.New(), txnId, leg.accountId, leg.units, leg.currency.Code, leg.currency.Exponent, leg.direction, userId)
+		if err != nil {
+			slog.ErrorContext(ctx, "error while creating "+leg.direction+" ledger entry", "error", err)
+			return "", err
+		}
+		event.Legs = append(event.Legs, eventLeg{AccountId: leg.accountId, Direction: leg.direction, Amount: leg.units, Currency: leg.currency.Code})
 	}
 
-	ledgerId2 := t.ledgerID.New()
-	_, err = tx.ExecContext(ctx, "INSERT INTO ledger_entries (id,  transaction_id, account_id, amount, direction, created_by) VALUES ($1, $2, $3, $4, $5, $6)",
-		ledgerId2, txnId, creditedAccountId, amount*100, "credit", userId)
-	if err != nil {
-		slog.ErrorContext(ctx, "error while creating credit ledger entry", "error", err)
+	if err := recordEvent(ctx, tx, EventTransactionCreated, string(txnId), event); err != nil {
 		return "", err
 	}
+	return string(txnId), nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return "", fmt.Errorf("error committing transaction: %w", err)
+// legBalanceChanges reads the balance of every account a set of legs touches and returns
+// the net change to each. Accounts whose legs cancel out are left untouched.
+func legBalanceChanges(ctx context.Context, tx *sql.Tx, legs []ledgerLeg) ([]balanceChange, error) {
+	deltas := make(map[string]int64)
+	var accountIds []string
+	for _, leg := range legs {
+		if _, ok := deltas[leg.accountId]; !ok {
+			accountIds = append(accountIds, leg.accountId)
+		}
+		if leg.direction == "debit" {
+			deltas[leg.accountId] -= leg.units
+		} else {
+			deltas[leg.accountId] += leg.units
+		}
 	}
 
-	return string(txnId), nil
+	changes := make([]balanceChange, 0, len(accountIds))
+	for _, accountId := range accountIds {
+		if deltas[accountId] == 0 {
+			continue
+		}
+		balance, err := readAccountBalance(ctx, tx, accountId)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, balanceChange{balance: balance, postedDelta: deltas[accountId]})
+	}
+	return changes, nil
 }
 
-// checkSufficientBalance checks if the account has sufficient balance to withdraw the amount
-func (t *TransactionRepository) checkSufficientBalance(ctx context.Context, tx *sql.Tx, accountId string, amount float64) (bool, error) {
-	var balance float64
-	err := tx.QueryRowContext(ctx, `
-        SELECT 
-            COALESCE(SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END), 0) AS balance
-        FROM
-            ledger_entries
-        WHERE
-            account_id = $1
-    `, accountId).Scan(&balance)
+// postingCurrency returns the currency shared by the debited and credited accounts and
+// rejects postings whose legs, or whose amount, are denominated in different currencies,
+// as well as postings that the state of either account does not allow
+func (t *TransactionRepository) postingCurrency(ctx context.Context, tx *sql.Tx, amount money.Money, debitedAccountId, creditedAccountId string) (currency.Currency, error) {
+	debitAccount, err := readPostingAccount(ctx, tx, debitedAccountId)
+	if err != nil {
+		return currency.Currency{}, err
+	}
+	creditAccount, err := readPostingAccount(ctx, tx, creditedAccountId)
 	if err != nil {
-		return false, err
+		return currency.Currency{}, err
+	}
+	if err := debitAccount.allows("debit"); err != nil {
+		return currency.Currency{}, err
+	}
+	if err := creditAccount.allows("credit"); err != nil {
+		return currency.Currency{}, err
+	}
+
+	if debitAccount.currency != creditAccount.currency {
+		return currency.Currency{}, fmt.Errorf("%w: account %s is in %s but account %s is in %s",
+			money.ErrCurrencyMismatch, debitedAccountId, debitAccount.currency, creditedAccountId, creditAccount.currency)
+	}
+	if amount.Currency != debitAccount.currency {
+		return currency.Currency{}, fmt.Errorf("%w: amount is in %s but accounts are in %s",
+			money.ErrCurrencyMismatch, amount.Currency, debitAccount.currency)
+	}
+	return currency.Lookup(debitAccount.currency)
+}
+
+// checkSufficientBalance checks if the account's available balance, extended by its
+// overdraft limit, covers the amount. Accounts with an unlimited overdraft always do.
+func (t *TransactionRepository) checkSufficientBalance(balance *accountBalance, units int64) bool {
+	if !balance.overdraftLimit.Valid {
+		return true
 	}
-	return balance >= amount*100, nil
+	return balance.available >= units-balance.overdraftLimit.Int64
 }
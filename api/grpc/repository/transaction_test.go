@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"log"
 	"testing"
 
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
 	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
 	"github.com/testcontainers/testcontainers-go"
 )
@@ -22,25 +25,56 @@ func TestTransactionRepository_DepositFunds(t *testing.T) {
 
 	tests := []struct {
 		name             string
-		amount           float64
+		amount           money.Money
 		userId           string
 		debitAccountId   string
 		creditAccountId  string
 		expectedIdLength int
 		wantErr          bool
+		wantErrIs        error
 	}{
 		{
 			name:             "successful deposit",
-			amount:           100,
+			amount:           usd(100),
 			userId:           "usr_1",
-			debitAccountId:   "acct_1",
-			creditAccountId:  "acct_2",
+			debitAccountId:   "acct_2",
+			creditAccountId:  "acct_1",
 			expectedIdLength: 20,
 			wantErr:          false,
 		},
+		{
+			name:             "deposit from internal to external account",
+			amount:           usd(100),
+			userId:           "usr_1",
+			debitAccountId:   "acct_1",
+			creditAccountId:  "acct_2",
+			expectedIdLength: 0,
+			wantErr:          true,
+			wantErrIs:        ErrInvalidPosting,
+		},
+		{
+			name:             "deposit into another user's account",
+			amount:           usd(100),
+			userId:           "usr_1",
+			debitAccountId:   "acct_2",
+			creditAccountId:  "acct_4",
+			expectedIdLength: 0,
+			wantErr:          true,
+			wantErrIs:        ErrAccountNotOwned,
+		},
+		{
+			name:             "user does not exist",
+			amount:           usd(100),
+			userId:           "usr_4",
+			debitAccountId:   "acct_2",
+			creditAccountId:  "acct_1",
+			expectedIdLength: 0,
+			wantErr:          true,
+			wantErrIs:        ErrUserNotFound,
+		},
 		{
 			name:             "insert error,  debit account does not exist",
-			amount:           100,
+			amount:           usd(100),
 			userId:           "usr_2",
 			debitAccountId:   "acct_3",
 			creditAccountId:  "acct_4",
@@ -49,7 +83,7 @@ func TestTransactionRepository_DepositFunds(t *testing.T) {
 		},
 		{
 			name:             "insert error, credit account does not exist",
-			amount:           100,
+			amount:           usd(100),
 			userId:           "usr_3",
 			debitAccountId:   "acct_5",
 			creditAccountId:  "acct_6",
@@ -61,13 +95,16 @@ func TestTransactionRepository_DepositFunds(t *testing.T) {
 	repo := NewTransactionRepository(db, "txn_", "le_")
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			txnId, err := repo.DepositFunds(context.Background(), tt.amount, tt.userId, tt.debitAccountId, tt.creditAccountId)
+			txnId, err := repo.DepositFunds(context.Background(), tt.amount, tt.userId, tt.debitAccountId, tt.creditAccountId, "")
 
 			if len(txnId) != int(tt.expectedIdLength) {
 				t.Errorf("expected result %d, got %d", tt.expectedIdLength, len(txnId))
 			}
 			if tt.wantErr {
 				assert.Error(t, err)
+				if tt.wantErrIs != nil {
+					assert.ErrorIs(t, err, tt.wantErrIs)
+				}
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expectedIdLength, len(txnId))
@@ -88,16 +125,17 @@ func TestTransactionRepository_WithdrawFunds(t *testing.T) {
 
 	tests := []struct {
 		name             string
-		amount           float64
+		amount           money.Money
 		userId           string
 		debitAccountId   string
 		creditAccountId  string
 		expectedIdLength int
 		wantErr          bool
+		wantErrIs        error
 	}{
 		{
 			name:             "successful withdraw",
-			amount:           100,
+			amount:           usd(100),
 			userId:           "usr_1",
 			debitAccountId:   "acct_1",
 			creditAccountId:  "acct_2",
@@ -106,16 +144,37 @@ func TestTransactionRepository_WithdrawFunds(t *testing.T) {
 		},
 		{
 			name:             "insufficient funds",
-			amount:           100,
+			amount:           usd(100),
 			userId:           "usr_2",
 			debitAccountId:   "acct_3",
-			creditAccountId:  "acct_4",
+			creditAccountId:  "acct_7",
+			expectedIdLength: 0,
+			wantErr:          true,
+			wantErrIs:        ErrInsufficientFunds,
+		},
+		{
+			name:             "withdraw from external to internal account",
+			amount:           usd(100),
+			userId:           "usr_1",
+			debitAccountId:   "acct_2",
+			creditAccountId:  "acct_1",
+			expectedIdLength: 0,
+			wantErr:          true,
+			wantErrIs:        ErrInvalidPosting,
+		},
+		{
+			name:             "withdraw to another user's external account",
+			amount:           usd(100),
+			userId:           "usr_1",
+			debitAccountId:   "acct_1",
+			creditAccountId:  "acct_7",
 			expectedIdLength: 0,
 			wantErr:          true,
+			wantErrIs:        ErrAccountNotOwned,
 		},
 		{
 			name:             "credit account does not exist",
-			amount:           100,
+			amount:           usd(100),
 			userId:           "usr_3",
 			debitAccountId:   "acct_5",
 			creditAccountId:  "acct_6",
@@ -127,13 +186,16 @@ func TestTransactionRepository_WithdrawFunds(t *testing.T) {
 	repo := NewTransactionRepository(db, "txn_", "le_")
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			txnId, err := repo.WithdrawFunds(context.Background(), tt.amount, tt.userId, tt.debitAccountId, tt.creditAccountId)
+			txnId, err := repo.WithdrawFunds(context.Background(), tt.amount, tt.userId, tt.debitAccountId, tt.creditAccountId, "")
 
 			if len(txnId) != tt.expectedIdLength {
 				t.Errorf("expected result %v, got %v", tt.expectedIdLength, len(txnId))
 			}
 			if tt.wantErr {
 				assert.Error(t, err)
+				if tt.wantErrIs != nil {
+					assert.ErrorIs(t, err, tt.wantErrIs)
+				}
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expectedIdLength, len(txnId))
@@ -154,39 +216,52 @@ func TestTransactionRepository_TransferFunds(t *testing.T) {
 
 	tests := []struct {
 		name             string
-		amount           float64
+		amount           money.Money
 		userId           string
 		debitAccountId   string
 		creditAccountId  string
 		expectedIdLength int
 		wantErr          bool
+		wantErrIs        error
 	}{
 		{
 			name:             "successful transfer",
-			amount:           100,
+			amount:           usd(100),
 			userId:           "usr_1",
 			debitAccountId:   "acct_1",
-			creditAccountId:  "acct_2",
+			creditAccountId:  "acct_3",
 			expectedIdLength: 20,
 			wantErr:          false,
 		},
 		{
 			name:             "insert error debit insufficient funds",
-			amount:           100,
+			amount:           usd(1000),
 			userId:           "usr_2",
 			debitAccountId:   "acct_3",
-			creditAccountId:  "acct_4",
+			creditAccountId:  "acct_1",
 			expectedIdLength: 0,
 			wantErr:          true,
+			wantErrIs:        ErrInsufficientFunds,
 		},
 		{
-			name:             "insert error debit insufficient funds",
-			amount:           100,
+			name:             "transfer from another user's account",
+			amount:           usd(100),
 			userId:           "usr_1",
 			debitAccountId:   "acct_5",
 			creditAccountId:  "acct_6",
 			expectedIdLength: 0,
 			wantErr:          true,
+			wantErrIs:        ErrAccountNotOwned,
+		},
+		{
+			name:             "transfer to an external account",
+			amount:           usd(100),
+			userId:           "usr_1",
+			debitAccountId:   "acct_1",
+			creditAccountId:  "acct_7",
+			expectedIdLength: 0,
+			wantErr:          true,
+			wantErrIs:        ErrInvalidPosting,
 		},
 	}
 
@@ -200,6 +275,9 @@ func TestTransactionRepository_TransferFunds(t *testing.T) {
 			}
 			if tt.wantErr {
 				assert.Error(t, err)
+				if tt.wantErrIs != nil {
+					assert.ErrorIs(t, err, tt.wantErrIs)
+				}
 			} else {
 				assert.NoError(t, err)
 				assert.Equal(t, tt.expectedIdLength, len(txnId))
@@ -208,6 +286,71 @@ func TestTransactionRepository_TransferFunds(t *testing.T) {
 	}
 }
 
+func TestTransactionRepository_CurrencyMismatch(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_multi_currency.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	tests := []struct {
+		name            string
+		amount          money.Money
+		userId          string
+		debitAccountId  string
+		creditAccountId string
+		wantErr         bool
+	}{
+		{
+			name:            "same currency transfer",
+			userId:          "usr_1",
+			amount:          usd(1),
+			debitAccountId:  "acct_1",
+			creditAccountId: "acct_2",
+			wantErr:         false,
+		},
+		{
+			name:            "debit and credit in different currencies",
+			userId:          "usr_1",
+			amount:          usd(1),
+			debitAccountId:  "acct_1",
+			creditAccountId: "acct_3",
+			wantErr:         true,
+		},
+		{
+			name:            "amount in a different currency than the accounts",
+			userId:          "usr_1",
+			amount:          money.Money{Units: 1, Currency: "EUR"},
+			debitAccountId:  "acct_1",
+			creditAccountId: "acct_2",
+			wantErr:         true,
+		},
+		{
+			name:            "transfer from an account in a different currency",
+			userId:          "usr_3",
+			amount:          usd(1),
+			debitAccountId:  "acct_3",
+			creditAccountId: "acct_2",
+			wantErr:         true,
+		},
+	}
+
+	repo := NewTransactionRepository(db, "txn_", "le_")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repo.TransferFunds(context.Background(), tt.amount, tt.userId, tt.debitAccountId, tt.creditAccountId)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestTransactionRepository_ListTransactions(t *testing.T) {
 	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_list_transactions.sql")
 	defer func(container testcontainers.Container) {
@@ -222,6 +365,9 @@ func TestTransactionRepository_ListTransactions(t *testing.T) {
 		name           string
 		filter         TransactionFilter
 		expectedResult []Transaction
+		// expectedCount is checked instead of expectedResult when it is set
+		expectedCount int
+		// expectedCursor is the ID of the transaction the next page continues from
 		expectedCursor string
 		wantErr        bool
 	}{
@@ -229,15 +375,14 @@ func TestTransactionRepository_ListTransactions(t *testing.T) {
 			name: "successful list for acct_1",
 			filter: TransactionFilter{
 				AccountID: strPtr("acct_1"),
-				Cursor:    nil,
 				Limit:     intPtr(5),
 			},
 			expectedResult: []Transaction{
-				{Id: "txn_11", Amount: 210, Status: "success"},
-				{Id: "txn_12", Amount: 220, Status: "success"},
-				{Id: "txn_14", Amount: 240, Status: "success"},
-				{Id: "txn_15", Amount: 250, Status: "success"},
-				{Id: "txn_17", Amount: 270, Status: "success"},
+				{Id: "txn_11", Amount: usd(210), Status: "success"},
+				{Id: "txn_12", Amount: usd(220), Status: "success"},
+				{Id: "txn_14", Amount: usd(240), Status: "success"},
+				{Id: "txn_15", Amount: usd(250), Status: "success"},
+				{Id: "txn_17", Amount: usd(270), Status: "success"},
 			},
 			expectedCursor: "txn_17",
 			wantErr:        false,
@@ -246,15 +391,14 @@ func TestTransactionRepository_ListTransactions(t *testing.T) {
 			name: "successful list for acct_2",
 			filter: TransactionFilter{
 				AccountID: strPtr("acct_2"),
-				Cursor:    nil,
 				Limit:     intPtr(5),
 			},
 			expectedResult: []Transaction{
-				{Id: "txn_1", Amount: 110, Status: "success"},
-				{Id: "txn_10", Amount: 200, Status: "success"},
-				{Id: "txn_12", Amount: 220, Status: "success"},
-				{Id: "txn_13", Amount: 230, Status: "success"},
-				{Id: "txn_15", Amount: 250, Status: "success"},
+				{Id: "txn_1", Amount: usd(110), Status: "success"},
+				{Id: "txn_10", Amount: usd(200), Status: "success"},
+				{Id: "txn_12", Amount: usd(220), Status: "success"},
+				{Id: "txn_13", Amount: usd(230), Status: "success"},
+				{Id: "txn_15", Amount: usd(250), Status: "success"},
 			},
 			expectedCursor: "txn_15",
 			wantErr:        false,
@@ -263,15 +407,14 @@ func TestTransactionRepository_ListTransactions(t *testing.T) {
 			name: "successful list for acct_3",
 			filter: TransactionFilter{
 				AccountID: strPtr("acct_3"),
-				Cursor:    nil,
 				Limit:     intPtr(5),
 			},
 			expectedResult: []Transaction{
-				{Id: "txn_1", Amount: 110, Status: "success"},
-				{Id: "txn_10", Amount: 200, Status: "success"},
-				{Id: "txn_11", Amount: 210, Status: "success"},
-				{Id: "txn_13", Amount: 230, Status: "success"},
-				{Id: "txn_14", Amount: 240, Status: "success"},
+				{Id: "txn_1", Amount: usd(110), Status: "success"},
+				{Id: "txn_10", Amount: usd(200), Status: "success"},
+				{Id: "txn_11", Amount: usd(210), Status: "success"},
+				{Id: "txn_13", Amount: usd(230), Status: "success"},
+				{Id: "txn_14", Amount: usd(240), Status: "success"},
 			},
 			expectedCursor: "txn_14",
 			wantErr:        false,
@@ -280,22 +423,98 @@ func TestTransactionRepository_ListTransactions(t *testing.T) {
 			name: "successful list with cursor",
 			filter: TransactionFilter{
 				AccountID: strPtr("acct_1"),
-				Cursor:    nil,
 				Limit:     intPtr(3),
 			},
 			expectedResult: []Transaction{
-				{Id: "txn_11", Amount: 210, Status: "success"},
-				{Id: "txn_12", Amount: 220, Status: "success"},
-				{Id: "txn_14", Amount: 240, Status: "success"},
+				{Id: "txn_11", Amount: usd(210), Status: "success"},
+				{Id: "txn_12", Amount: usd(220), Status: "success"},
+				{Id: "txn_14", Amount: usd(240), Status: "success"},
 			},
 			expectedCursor: "txn_14",
 			wantErr:        false,
 		},
+		{
+			name: "descending list for acct_1",
+			filter: TransactionFilter{
+				AccountID:  strPtr("acct_1"),
+				Limit:      intPtr(3),
+				Descending: true,
+			},
+			expectedResult: []Transaction{
+				{Id: "txn_9", Amount: usd(190), Status: "success"},
+				{Id: "txn_8", Amount: usd(180), Status: "success"},
+				{Id: "txn_6", Amount: usd(160), Status: "success"},
+			},
+			expectedCursor: "txn_6",
+		},
+		{
+			name: "credits to acct_1",
+			filter: TransactionFilter{
+				AccountID: strPtr("acct_1"),
+				Limit:     intPtr(3),
+				Direction: strPtr("credit"),
+			},
+			expectedResult: []Transaction{
+				{Id: "txn_11", Amount: usd(210), Status: "success"},
+				{Id: "txn_14", Amount: usd(240), Status: "success"},
+				{Id: "txn_17", Amount: usd(270), Status: "success"},
+			},
+			expectedCursor: "txn_17",
+		},
+		{
+			name: "acct_1 transactions with acct_2",
+			filter: TransactionFilter{
+				AccountID:             strPtr("acct_1"),
+				Limit:                 intPtr(3),
+				CounterpartyAccountID: strPtr("acct_2"),
+			},
+			expectedResult: []Transaction{
+				{Id: "txn_12", Amount: usd(220), Status: "success"},
+				{Id: "txn_15", Amount: usd(250), Status: "success"},
+				{Id: "txn_18", Amount: usd(280), Status: "success"},
+			},
+			expectedCursor: "txn_18",
+		},
+		{
+			name: "acct_1 transactions within an amount range",
+			filter: TransactionFilter{
+				AccountID: strPtr("acct_1"),
+				Limit:     intPtr(5),
+				MinAmount: moneyPtr(usd(200)),
+				MaxAmount: moneyPtr(usd(230)),
+			},
+			expectedResult: []Transaction{
+				{Id: "txn_11", Amount: usd(210), Status: "success"},
+				{Id: "txn_12", Amount: usd(220), Status: "success"},
+			},
+			expectedCursor: "",
+		},
+		{
+			name: "no reversed transactions",
+			filter: TransactionFilter{
+				AccountID: strPtr("acct_1"),
+				Status:    strPtr("reversed"),
+			},
+			expectedResult: []Transaction{},
+			expectedCursor: "",
+		},
+		{
+			name: "default page size fits every acct_3 transaction",
+			filter: TransactionFilter{
+				AccountID: strPtr("acct_3"),
+			},
+			expectedCount:  34,
+			expectedCursor: "",
+		},
+		{
+			name:    "an account is required",
+			filter:  TransactionFilter{Limit: intPtr(5)},
+			wantErr: true,
+		},
 		{
 			name: "no transactions found",
 			filter: TransactionFilter{
 				AccountID: strPtr("non_existent_account"),
-				Cursor:    nil,
 				Limit:     intPtr(5),
 			},
 			expectedResult: []Transaction{},
@@ -307,12 +526,24 @@ func TestTransactionRepository_ListTransactions(t *testing.T) {
 	repo := NewTransactionRepository(db, "txn_", "le_")
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			results, cursor, err := repo.ListTransactions(context.Background(), &tt.filter)
+			results, page, err := repo.ListTransactions(context.Background(), &tt.filter)
+			var cursor string
+			if page.Next != nil {
+				cursor = page.Next.ID
+			}
 
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
+				if tt.expectedCount > 0 {
+					assert.Len(t, results, tt.expectedCount)
+					assert.Equal(t, tt.expectedCursor, cursor)
+					return
+				}
+				if !assert.Len(t, results, len(tt.expectedResult)) {
+					return
+				}
 				for i, result := range results {
 					assert.Equal(t, tt.expectedResult[i].Id, result.Id)
 					assert.Equal(t, tt.expectedResult[i].Amount, result.Amount)
@@ -324,6 +555,117 @@ func TestTransactionRepository_ListTransactions(t *testing.T) {
 	}
 }
 
+func TestTransactionRepository_ListTransactionsLegs(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_list_transactions.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	repo := NewTransactionRepository(db, "txn_", "le_")
+	for _, tt := range []struct {
+		accountId        string
+		wantDirection    string
+		wantCounterparty string
+	}{
+		{"acct_1", "credit", "acct_3"},
+		{"acct_3", "debit", "acct_1"},
+	} {
+		t.Run(tt.accountId, func(t *testing.T) {
+			// txn_11 moves 210 from acct_3 to acct_1
+			txns, _, err := repo.ListTransactions(context.Background(), &TransactionFilter{
+				AccountID:             strPtr(tt.accountId),
+				CounterpartyAccountID: strPtr(tt.wantCounterparty),
+				Limit:                 intPtr(1),
+				IncludeLegs:           true,
+			})
+			assert.NoError(t, err)
+			if !assert.Len(t, txns, 1) {
+				return
+			}
+			txn := txns[0]
+			assert.Equal(t, "txn_11", txn.Id)
+			assert.Equal(t, tt.accountId, txn.AccountId)
+			assert.Equal(t, tt.wantDirection, txn.Direction)
+			assert.Equal(t, usd(210), txn.Amount)
+			assert.Equal(t, tt.wantCounterparty, txn.CounterpartyAccountId)
+			assert.False(t, txn.CreatedAt.IsZero())
+			assert.Equal(t, []LedgerEntry{
+				{Id: "le_21", AccountId: "acct_3", Direction: "debit", Amount: usd(210)},
+				{Id: "le_22", AccountId: "acct_1", Direction: "credit", Amount: usd(210)},
+			}, txn.Legs)
+		})
+	}
+}
+
+func TestTransactionRepository_ListTransactionsPaging(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_list_transactions.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	repo := NewTransactionRepository(db, "txn_", "le_")
+	ids := func(txns []Transaction) []string {
+		var ids []string
+		for _, txn := range txns {
+			ids = append(ids, txn.Id)
+		}
+		return ids
+	}
+
+	for _, descending := range []bool{false, true} {
+		t.Run(fmt.Sprintf("descending=%v", descending), func(t *testing.T) {
+			all, page, err := repo.ListTransactions(ctx, &TransactionFilter{AccountID: strPtr("acct_1"), Limit: intPtr(100), Descending: descending})
+			assert.NoError(t, err)
+			assert.Len(t, all, 33)
+			assert.Nil(t, page.Next)
+			assert.Nil(t, page.Prev)
+
+			// Page forward to the end, then back to the start
+			var pages [][]string
+			filter := TransactionFilter{AccountID: strPtr("acct_1"), Limit: intPtr(10), Descending: descending}
+			for {
+				txns, page, err := repo.ListTransactions(ctx, &filter)
+				assert.NoError(t, err)
+				assert.Equal(t, len(pages) > 0, page.Prev != nil)
+				pages = append(pages, ids(txns))
+				if page.Next == nil {
+					filter.Position, filter.Backward = page.Prev, true
+					break
+				}
+				filter.Position = page.Next
+			}
+			assert.Len(t, pages, 4)
+
+			var forward []string
+			for _, p := range pages {
+				forward = append(forward, p...)
+			}
+			assert.Equal(t, ids(all), forward)
+
+			for i := len(pages) - 2; i >= 0; i-- {
+				txns, page, err := repo.ListTransactions(ctx, &filter)
+				assert.NoError(t, err)
+				assert.Equal(t, pages[i], ids(txns))
+				assert.NotNil(t, page.Next)
+				assert.Equal(t, i > 0, page.Prev != nil)
+				if page.Prev == nil {
+					break
+				}
+				filter.Position = page.Prev
+			}
+		})
+	}
+}
+
 // Helper functions to create pointers
 func strPtr(s string) *string {
 	return &s
@@ -332,3 +674,58 @@ func strPtr(s string) *string {
 func intPtr(i int) *int {
 	return &i
 }
+
+func usd(units int64) money.Money {
+	return money.Money{Units: units, Currency: "USD"}
+}
+
+func moneyPtr(m money.Money) *money.Money {
+	return &m
+}
+
+func TestCheckSufficientBalance(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance *accountBalance
+		units   int64
+		want    bool
+	}{
+		{
+			name:    "covered by available balance",
+			balance: &accountBalance{available: 100, overdraftLimit: sql.NullInt64{Valid: true}},
+			units:   100,
+			want:    true,
+		},
+		{
+			name:    "no overdraft",
+			balance: &accountBalance{available: 100, overdraftLimit: sql.NullInt64{Valid: true}},
+			units:   101,
+			want:    false,
+		},
+		{
+			name:    "covered by overdraft",
+			balance: &accountBalance{available: 100, overdraftLimit: sql.NullInt64{Int64: 50, Valid: true}},
+			units:   150,
+			want:    true,
+		},
+		{
+			name:    "beyond overdraft",
+			balance: &accountBalance{available: -20, overdraftLimit: sql.NullInt64{Int64: 50, Valid: true}},
+			units:   31,
+			want:    false,
+		},
+		{
+			name:    "unlimited overdraft",
+			balance: &accountBalance{available: -1000000},
+			units:   1000000,
+			want:    true,
+		},
+	}
+
+	repo := &TransactionRepository{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, repo.checkSufficientBalance(tt.balance, tt.units))
+		})
+	}
+}
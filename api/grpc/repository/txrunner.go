@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres error codes for failures that are safe to retry from the start of the transaction
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// ErrRetriesExhausted is returned when a transaction kept losing races with concurrent
+// transactions until it ran out of attempts or time. It wraps the last failure, and nothing
+// was committed, so the request is safe to retry.
+var ErrRetriesExhausted = errors.New("transaction kept conflicting with concurrent transactions")
+
+// txStats exposes retry counters for money movement transactions via expvar
+var txStats = expvar.NewMap("repository_tx")
+
+// RetryPolicy bounds how often and how quickly a failed transaction is retried
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used by repositories unless configured otherwise
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    500 * time.Millisecond,
+}
+
+// runInTx runs fn in a database transaction and commits it. When Postgres aborts the
+// transaction with a serialization failure or deadlock, or a balance version check fails,
+// the whole transaction is retried with jittered exponential backoff. fn must therefore be
+// safe to run more than once. Retries stop early if the context would expire first.
+// Under WithIdempotencyClaim, the transaction also marks the claimed idempotency key as used.
+func runInTx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, policy RetryPolicy, fn func(tx *sql.Tx) error) error {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = runOnce(ctx, db, opts, fn)
+		if err == nil {
+			if attempt > 1 {
+				txStats.Add("retried_success", 1)
+				slog.InfoContext(ctx, "transaction succeeded after retry", "attempts", attempt)
+			}
+			return nil
+		}
+		if !isRetryable(err) {
+			return err
+		}
+
+		txStats.Add("retries", 1)
+		if attempt >= maxAttempts {
+			txStats.Add("exhausted", 1)
+			slog.ErrorContext(ctx, "transaction retries exhausted", "attempts", attempt, "error", err)
+			return fmt.Errorf("%w: failed after %d attempts: %w", ErrRetriesExhausted, attempt, err)
+		}
+
+		delay := backoff(policy, attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			slog.WarnContext(ctx, "not retrying transaction, context deadline too close", "attempts", attempt, "error", err)
+			return fmt.Errorf("%w: failed after %d attempts: %w", ErrRetriesExhausted, attempt, err)
+		}
+		slog.WarnContext(ctx, "retrying transaction", "attempt", attempt, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: failed after %d attempts: %w", ErrRetriesExhausted, attempt, errors.Join(err, ctx.Err()))
+		case <-timer.C:
+		}
+	}
+}
+
+func runOnce(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := useIdempotencyClaim(ctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+	return nil
+}
+
+// isRetryable reports whether err means the transaction lost a race and can be run again
+func isRetryable(err error) bool {
+	if errors.Is(err, ErrConcurrentUpdate) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == serializationFailure || pqErr.Code == deadlockDetected
+	}
+	return false
+}
+
+// backoff returns a random delay between zero and the exponential backoff for the attempt
+// ("full jitter"), so that transactions that collided do not collide again in lockstep
+func backoff(policy RetryPolicy, attempt int) time.Duration {
+	ceiling := policy.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > policy.MaxDelay {
+		ceiling = policy.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pq.Error{Code: serializationFailure}, true},
+		{"deadlock", &pq.Error{Code: deadlockDetected}, true},
+		{"wrapped serialization failure", fmt.Errorf("error committing transaction: %w", &pq.Error{Code: serializationFailure}), true},
+		{"balance version conflict", fmt.Errorf("%w: account acct_1", ErrConcurrentUpdate), true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"insufficient balance", errors.New("insufficient balance in account acct_1"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isRetryable(tt.err))
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 100 * time.Millisecond}
+	for attempt := 1; attempt <= 100; attempt++ {
+		delay := backoff(policy, attempt)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, policy.MaxDelay)
+		if attempt == 1 {
+			assert.LessOrEqual(t, delay, policy.BaseDelay)
+		}
+	}
+}
+
+func TestRunInTx_ConcurrentWithdrawals(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_withdraw_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	repo := NewTransactionRepository(db, "txn_", "le_")
+	// Every goroutine races for the same balance row, so allow enough attempts for all of them to get a turn
+	repo.retry = RetryPolicy{MaxAttempts: 100, BaseDelay: 5 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	// acct_1 holds 150, so exactly 15 withdrawals of 10 can succeed
+	const workers = 25
+	var (
+		wg           sync.WaitGroup
+		mu           sync.Mutex
+		succeeded    int
+		insufficient int
+		unexpected   []error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.WithdrawFunds(context.Background(), usd(10), "usr_1", "acct_1", "acct_2", "")
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case strings.Contains(err.Error(), "insufficient balance"):
+				insufficient++
+			default:
+				unexpected = append(unexpected, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Empty(t, unexpected)
+	assert.Equal(t, 15, succeeded)
+	assert.Equal(t, workers-15, insufficient)
+
+	accounts := NewAccountRepository(db, "acct_")
+	balance, err := accounts.GetAccountBalance(context.Background(), "acct_1", time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), balance.Amount.Units)
+
+	// The projection must agree with the ledger after all the contention
+	drift, err := accounts.RebuildBalances(context.Background(), true)
+	assert.NoError(t, err)
+	assert.Empty(t, drift)
+}
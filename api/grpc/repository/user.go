@@ -4,17 +4,34 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
 )
 
+// User states
+const (
+	UserStateActive      = "active"
+	UserStateDeactivated = "deactivated"
+)
+
+// DefaultUserPageSize is the number of users listed when no limit is given
+const DefaultUserPageSize = 50
+
 type User struct {
 	Id                 string
 	Email              string
 	Name               string
 	IntLedgerAccountId sql.NullString
 	ExtLedgerAccountId sql.NullString
+	// Currency the user's ledger accounts are denominated in
+	Currency  string
+	UserState string
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 type UserRepository struct {
@@ -27,46 +44,215 @@ func NewUserRepository(db *sql.DB, accountRepo *AccountRepository, prefix string
 	return &UserRepository{db: db, accountRepo: accountRepo, ID: identifier.ID(prefix)}
 }
 
+// CreateUser creates a user together with their internal and external ledger accounts in a
+// single transaction, so a failure part way through, e.g. a duplicate email, leaves nothing
+// behind. Both accounts are linked back to the user.
 func (r *UserRepository) CreateUser(ctx context.Context, user *User) (*User, error) {
+	var created *User
+	err := runInTx(ctx, r.db, nil, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		var err error
+		created, err = r.createUser(ctx, tx, user)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *UserRepository) createUser(ctx context.Context, q Querier, user *User) (*User, error) {
 	var userId string
+	var createdAt, updatedAt time.Time
+	idUser := string(r.ID.New())
+
 	// Create internal and external ledger accounts
 	intAccount := &Account{
+		Id:           string(r.accountRepo.ID.New()),
+		UserId:       idUser,
 		AccountState: "open",
 		AccountType:  "debit",
+		Currency:     user.Currency,
 	}
+	// The external account stands in for the outside world and can go negative without limit
 	extAccount := &Account{
-		AccountState: "open",
-		AccountType:  "credit",
+		Id:                 string(r.accountRepo.ID.New()),
+		UserId:             idUser,
+		AccountState:       "open",
+		AccountType:        "credit",
+		Currency:           user.Currency,
+		UnlimitedOverdraft: true,
 	}
 
-	intAccountId, err := r.accountRepo.CreateAccount(ctx, intAccount)
+	// The user is inserted first because the accounts reference it
+	err := q.QueryRowContext(ctx, `
+        INSERT INTO users (id, email, name, int_ledger_account_id, ext_ledger_account_id)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at, updated_at
+    `, idUser, user.Email, user.Name, intAccount.Id, extAccount.Id).Scan(&userId, &createdAt, &updatedAt)
+	if isUniqueViolation(err, "users_email_key") {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateEmail, user.Email)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error while creating user", "error", err)
+		return nil, err
+	}
+
+	intAccountId, err := r.accountRepo.CreateAccountTx(ctx, q, intAccount)
 	if err != nil {
 		slog.ErrorContext(ctx, "error while creating internal account", "error", err)
 		return nil, err
 	}
-	extAccountId, err := r.accountRepo.CreateAccount(ctx, extAccount)
+	extAccountId, err := r.accountRepo.CreateAccountTx(ctx, q, extAccount)
 	if err != nil {
 		slog.ErrorContext(ctx, "error while creating external account", "error", err)
 		return nil, err
 	}
 
-	idUser := r.ID.New()
-	user.IntLedgerAccountId = sql.NullString{String: intAccountId, Valid: true}
-	user.ExtLedgerAccountId = sql.NullString{String: extAccountId, Valid: true}
+	return &User{
+		Id:                 userId,
+		Email:              user.Email,
+		Name:               user.Name,
+		IntLedgerAccountId: sql.NullString{String: intAccountId, Valid: true},
+		ExtLedgerAccountId: sql.NullString{String: extAccountId, Valid: true},
+		Currency:           intAccount.Currency,
+		UserState:          UserStateActive,
+		CreatedAt:          createdAt,
+		UpdatedAt:          updatedAt,
+	}, nil
+}
+
+// userColumns are the columns scanned by scanUser. The currency is that of the user's
+// internal account.
+const userColumns = `
+	u.id, u.email, u.name, u.int_ledger_account_id, u.ext_ledger_account_id,
+	COALESCE(a.currency, ''), u.user_state, u.created_at, u.updated_at
+`
 
-	err = r.db.QueryRowContext(ctx, `
-        INSERT INTO users (id, email, name, int_ledger_account_id, ext_ledger_account_id)
-        VALUES ($1, $2, $3, $4, $5)
-        RETURNING id
-    `, idUser, user.Email, user.Name, user.IntLedgerAccountId, user.ExtLedgerAccountId).Scan(&userId)
+const selectUsers = `SELECT ` + userColumns + `
+	FROM users u
+	LEFT JOIN accounts a ON a.id = u.int_ledger_account_id
+`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	var u User
+	err := row.Scan(&u.Id, &u.Email, &u.Name, &u.IntLedgerAccountId, &u.ExtLedgerAccountId,
+		&u.Currency, &u.UserState, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
-		slog.ErrorContext(ctx, "error while creating user", "error", err)
 		return nil, err
 	}
+	return &u, nil
+}
 
-	return &User{
-		Id:                 userId,
-		IntLedgerAccountId: user.IntLedgerAccountId,
-		ExtLedgerAccountId: user.ExtLedgerAccountId,
-	}, err
+// GetUser returns a user by ID
+func (r *UserRepository) GetUser(ctx context.Context, userId string) (*User, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx, selectUsers+"WHERE u.id = $1", userId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, userId)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error while getting user", "error", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetUserByEmail returns the user with an email, compared case-insensitively
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx, selectUsers+"WHERE u.email = $1", email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: no user with email %s", ErrUserNotFound, email)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "error while getting user by email", "error", err)
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListUsers lists users in ID order, starting after the user with ID cursor. The returned
+// cursor is empty on the last page.
+func (r *UserRepository) ListUsers(ctx context.Context, cursor string, limit int) ([]User, string, error) {
+	if limit <= 0 {
+		limit = DefaultUserPageSize
+	}
+
+	// One extra row tells whether there is another page
+	rows, err := r.db.QueryContext(ctx, selectUsers+"WHERE u.id > $1 ORDER BY u.id LIMIT $2", cursor, limit+1)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while listing users", "error", err)
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, "", fmt.Errorf("error scanning user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("error iterating users: %w", err)
+	}
+
+	var nextCursor string
+	if len(users) > limit {
+		users = users[:limit]
+		nextCursor = users[limit-1].Id
+	}
+	return users, nextCursor, nil
+}
+
+// UpdateUser changes a user's name. The previous row is recorded in the user's history by
+// the users history trigger.
+func (r *UserRepository) UpdateUser(ctx context.Context, userId, name string) (*User, error) {
+	return r.updateUser(ctx, userId, func(tx *sql.Tx, user *User) error {
+		user.Name = name
+		return tx.QueryRowContext(ctx, "UPDATE users SET name = $2, updated_by = $1 WHERE id = $1 RETURNING updated_at",
+			userId, name).Scan(&user.UpdatedAt)
+	})
+}
+
+// DeactivateUser stops a user from moving money. Their accounts, balances and history are
+// kept, and money can still be sent to them.
+func (r *UserRepository) DeactivateUser(ctx context.Context, userId string) (*User, error) {
+	return r.updateUser(ctx, userId, func(tx *sql.Tx, user *User) error {
+		user.UserState = UserStateDeactivated
+		return tx.QueryRowContext(ctx, "UPDATE users SET user_state = $2, updated_by = $1 WHERE id = $1 RETURNING updated_at",
+			userId, UserStateDeactivated).Scan(&user.UpdatedAt)
+	})
+}
+
+// updateUser locks an active user and applies update to them
+func (r *UserRepository) updateUser(ctx context.Context, userId string, update func(tx *sql.Tx, user *User) error) (*User, error) {
+	var user *User
+	err := runInTx(ctx, r.db, &sql.TxOptions{Isolation: sql.LevelReadCommitted}, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		var err error
+		user, err = scanUser(tx.QueryRowContext(ctx, selectUsers+"WHERE u.id = $1 FOR UPDATE OF u", userId))
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrUserNotFound, userId)
+		}
+		if err != nil {
+			return err
+		}
+		if user.UserState != UserStateActive {
+			return fmt.Errorf("%w: %s", ErrUserDeactivated, userId)
+		}
+
+		if err := update(tx, user); err != nil {
+			slog.ErrorContext(ctx, "error while updating user", "error", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
 }
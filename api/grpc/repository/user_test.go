@@ -63,3 +63,157 @@ func TestCreateUser(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	userRepo := NewUserRepository(db, NewAccountRepository(db, "acct_"), "usr_")
+	_, err := userRepo.CreateUser(context.Background(), &User{Email: "taken@example.com", Name: "First User"})
+	assert.NoError(t, err)
+
+	_, err = userRepo.CreateUser(context.Background(), &User{Email: "taken@example.com", Name: "Second User"})
+	assert.ErrorIs(t, err, ErrDuplicateEmail)
+
+	// The failed user's ledger accounts are rolled back with it
+	var accounts int
+	assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM accounts").Scan(&accounts))
+	assert.Equal(t, 2, accounts)
+}
+
+func TestCreateUser_LinksAccounts(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	userRepo := NewUserRepository(db, NewAccountRepository(db, "acct_"), "usr_")
+
+	t.Run("accounts belong to the user", func(t *testing.T) {
+		user, err := userRepo.CreateUser(ctx, &User{Email: "linked@example.com", Name: "Linked User", Currency: "EUR"})
+		assert.NoError(t, err)
+		assert.NotEqual(t, user.IntLedgerAccountId.String, user.ExtLedgerAccountId.String)
+
+		rows, err := db.Query("SELECT id, user_id, currency FROM accounts WHERE id IN ($1, $2)",
+			user.IntLedgerAccountId.String, user.ExtLedgerAccountId.String)
+		assert.NoError(t, err)
+		defer rows.Close()
+		var found int
+		for rows.Next() {
+			var id, userId, currency string
+			assert.NoError(t, rows.Scan(&id, &userId, &currency))
+			assert.Equal(t, user.Id, userId)
+			assert.Equal(t, "EUR", currency)
+			found++
+		}
+		assert.Equal(t, 2, found)
+	})
+
+	t.Run("unsupported currency creates nothing", func(t *testing.T) {
+		_, err := userRepo.CreateUser(ctx, &User{Email: "nothing@example.com", Name: "No User", Currency: "XYZ"})
+		assert.Error(t, err)
+
+		var users int
+		assert.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users WHERE email = 'nothing@example.com'").Scan(&users))
+		assert.Zero(t, users)
+	})
+}
+
+func TestUserManagement(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	userRepo := NewUserRepository(db, NewAccountRepository(db, "acct_"), "usr_")
+	transactions := NewTransactionRepository(db, "txn_", "le_")
+
+	var created []*User
+	for _, email := range []string{"ada@example.com", "grace@example.com", "linus@example.com"} {
+		user, err := userRepo.CreateUser(ctx, &User{Email: email, Name: email})
+		assert.NoError(t, err)
+		created = append(created, user)
+	}
+	user := created[0]
+
+	t.Run("get user", func(t *testing.T) {
+		got, err := userRepo.GetUser(ctx, user.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "ada@example.com", got.Email)
+		assert.Equal(t, user.IntLedgerAccountId, got.IntLedgerAccountId)
+		assert.Equal(t, "USD", got.Currency)
+		assert.Equal(t, UserStateActive, got.UserState)
+
+		_, err = userRepo.GetUser(ctx, "usr_missing")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("get user by email ignores case", func(t *testing.T) {
+		got, err := userRepo.GetUserByEmail(ctx, "ADA@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, user.Id, got.Id)
+
+		_, err = userRepo.GetUserByEmail(ctx, "nobody@example.com")
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("update is recorded in history", func(t *testing.T) {
+		updated, err := userRepo.UpdateUser(ctx, user.Id, "Ada Lovelace")
+		assert.NoError(t, err)
+		assert.Equal(t, "Ada Lovelace", updated.Name)
+
+		var previousName, updatedBy string
+		err = db.QueryRow("SELECT history[array_length(history, 1)]->>'name', updated_by FROM users WHERE id = $1", user.Id).
+			Scan(&previousName, &updatedBy)
+		assert.NoError(t, err)
+		assert.Equal(t, "ada@example.com", previousName)
+		assert.Equal(t, user.Id, updatedBy)
+	})
+
+	t.Run("list users page by page", func(t *testing.T) {
+		var ids []string
+		cursor := ""
+		for page := 0; page < 3; page++ {
+			users, next, err := userRepo.ListUsers(ctx, cursor, 2)
+			assert.NoError(t, err)
+			for _, u := range users {
+				ids = append(ids, u.Id)
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		assert.Len(t, ids, 3)
+		assert.IsIncreasing(t, ids)
+	})
+
+	t.Run("deactivated user cannot move money or be updated", func(t *testing.T) {
+		deactivated, err := userRepo.DeactivateUser(ctx, user.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, UserStateDeactivated, deactivated.UserState)
+
+		_, err = transactions.DepositFunds(ctx, usd(100), user.Id, user.ExtLedgerAccountId.String, user.IntLedgerAccountId.String, "")
+		assert.ErrorIs(t, err, ErrUserDeactivated)
+		_, err = userRepo.UpdateUser(ctx, user.Id, "Ada")
+		assert.ErrorIs(t, err, ErrUserDeactivated)
+		_, err = userRepo.DeactivateUser(ctx, user.Id)
+		assert.ErrorIs(t, err, ErrUserDeactivated)
+	})
+}
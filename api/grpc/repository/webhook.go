@@ -0,0 +1,456 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
+)
+
+// Delivery states of a webhook delivery. A pending delivery is attempted until it succeeds
+// or runs out of attempts and is dead.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryDead      = "dead"
+)
+
+// webhookSecretPrefix marks webhook signing secrets so they are recognisable when leaked
+const webhookSecretPrefix = "whsec_"
+
+// WebhookEndpoint is a URL a user has registered to be sent the ledger events about their
+// accounts
+type WebhookEndpoint struct {
+	Id     string
+	UserId string
+	Url    string
+	// Secret signs every delivery to the endpoint. It is only read when the endpoint is
+	// created and when deliveries are made.
+	Secret string
+	// EventTypes the endpoint is sent; empty means every type
+	EventTypes []string
+	CreatedAt  time.Time
+	DeletedAt  *time.Time
+}
+
+// WebhookDelivery is a ledger event to be sent to an endpoint, with the outcome of the last
+// attempt to send it
+type WebhookDelivery struct {
+	Id         int64
+	EndpointId string
+	EventId    int64
+	EventType  string
+	State      string
+	Attempts   int
+	// NextAttemptAt is when a pending delivery is attempted next
+	NextAttemptAt time.Time
+	LastAttemptAt *time.Time
+	// LastStatusCode is the HTTP status of the last response, 0 if there was none
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// DueWebhookDelivery is a delivery claimed for an attempt, with what the attempt needs
+type DueWebhookDelivery struct {
+	WebhookDelivery
+	Url    string
+	Secret string
+	Event  LedgerEvent
+}
+
+// WebhookAttempt is the outcome of an attempt to deliver a webhook
+type WebhookAttempt struct {
+	// StatusCode is the HTTP status of the response, 0 if there was none
+	StatusCode int
+	// Error says why the attempt failed; it is empty if the delivery succeeded
+	Error string
+	// RetryAfter is how long until a failed delivery is attempted again. A failed delivery
+	// that is not to be retried is dead.
+	RetryAfter time.Duration
+}
+
+// WebhookDeliveryFilter narrows the deliveries listed by ListWebhookDeliveries
+type WebhookDeliveryFilter struct {
+	EndpointId string
+	// UserId is the user the endpoint must belong to
+	UserId string
+	// State matches every state when it is empty
+	State string
+	// Cursor is the ID of the last delivery on the previous page
+	Cursor int64
+	Limit  int
+}
+
+// DefaultWebhookDeliveryPageSize is the number of deliveries listed when no limit is given
+const DefaultWebhookDeliveryPageSize = 50
+
+type WebhookRepository struct {
+	db *sql.DB
+	ID identifier.ID
+}
+
+func NewWebhookRepository(db *sql.DB, prefix string) *WebhookRepository {
+	return &WebhookRepository{db: db, ID: identifier.ID(prefix)}
+}
+
+// CreateWebhookEndpoint registers an endpoint for a user, who must exist and be active, and
+// generates its signing secret
+func (w *WebhookRepository) CreateWebhookEndpoint(ctx context.Context, userId, url string, eventTypes []string) (*WebhookEndpoint, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("error generating webhook secret: %w", err)
+	}
+	endpoint := &WebhookEndpoint{
+		Id:         string(w.ID.New()),
+		UserId:     userId,
+		Url:        url,
+		Secret:     webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secret),
+		EventTypes: eventTypes,
+	}
+	if endpoint.EventTypes == nil {
+		endpoint.EventTypes = []string{}
+	}
+
+	err := runInTx(ctx, w.db, nil, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		if err := checkAccountOwner(ctx, tx, userId); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO webhook_endpoints (id, user_id, url, secret, event_types) VALUES ($1, $2, $3, $4, $5)
+			RETURNING created_at
+		`, endpoint.Id, userId, url, endpoint.Secret, pq.Array(endpoint.EventTypes)).Scan(&endpoint.CreatedAt)
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error while creating webhook endpoint", "error", err)
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// ListWebhookEndpoints lists a user's endpoints that have not been deleted, oldest first.
+// Their secrets are left out.
+func (w *WebhookRepository) ListWebhookEndpoints(ctx context.Context, userId string) ([]WebhookEndpoint, error) {
+	rows, err := w.db.QueryContext(ctx, `
+		SELECT id, user_id, url, event_types, created_at, deleted_at
+		FROM webhook_endpoints
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY id
+	`, userId)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while listing webhook endpoints", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []WebhookEndpoint
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook endpoint: %w", err)
+		}
+		endpoints = append(endpoints, *endpoint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// DeleteWebhookEndpoint stops events from being sent to one of a user's endpoints, including
+// its pending deliveries. The endpoint's deliveries stay in the log.
+func (w *WebhookRepository) DeleteWebhookEndpoint(ctx context.Context, endpointId, userId string) (*WebhookEndpoint, error) {
+	var endpoint *WebhookEndpoint
+	err := runInTx(ctx, w.db, nil, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		existing, err := readOwnedWebhookEndpoint(ctx, tx, userId, endpointId, "FOR UPDATE")
+		if err != nil {
+			return err
+		}
+		if existing.DeletedAt != nil {
+			return fmt.Errorf("%w: %s was deleted", ErrWebhookEndpointNotFound, endpointId)
+		}
+		endpoint, err = scanWebhookEndpoint(tx.QueryRowContext(ctx, `
+			UPDATE webhook_endpoints SET deleted_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+			RETURNING id, user_id, url, event_types, created_at, deleted_at
+		`, endpointId))
+		return err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error while deleting webhook endpoint", "error", err)
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// readOwnedWebhookEndpoint reads an endpoint of the user, deleted or not, locking it with lock
+func readOwnedWebhookEndpoint(ctx context.Context, q Querier, userId, endpointId, lock string) (*WebhookEndpoint, error) {
+	endpoint, err := scanWebhookEndpoint(q.QueryRowContext(ctx, `
+		SELECT id, user_id, url, event_types, created_at, deleted_at
+		FROM webhook_endpoints
+		WHERE id = $1 `+lock, endpointId))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrWebhookEndpointNotFound, endpointId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading webhook endpoint %s: %w", endpointId, err)
+	}
+	if endpoint.UserId != userId {
+		return nil, fmt.Errorf("%w: webhook endpoint %s is not user %s's", ErrWebhookEndpointNotOwned, endpointId, userId)
+	}
+	return endpoint, nil
+}
+
+func scanWebhookEndpoint(row rowScanner) (*WebhookEndpoint, error) {
+	var endpoint WebhookEndpoint
+	var deletedAt sql.NullTime
+	err := row.Scan(&endpoint.Id, &endpoint.UserId, &endpoint.Url, pq.Array(&endpoint.EventTypes), &endpoint.CreatedAt, &deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.DeletedAt = nullTime(deletedAt)
+	return &endpoint, nil
+}
+
+// ScheduleWebhookDeliveries creates a pending delivery of each event to every endpoint that
+// is sent its type and belongs to the owner of an account the event is about. Events that
+// were scheduled before are skipped, so the same events can be scheduled again safely. It
+// returns how many deliveries were created.
+func (w *WebhookRepository) ScheduleWebhookDeliveries(ctx context.Context, events []LedgerEvent) (int, error) {
+	var scheduled int
+	err := runInTx(ctx, w.db, nil, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		scheduled = 0
+		for _, e := range events {
+			res, err := tx.ExecContext(ctx, `
+				INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type)
+				SELECT w.id, $1, $2
+				FROM webhook_endpoints w
+				WHERE w.deleted_at IS NULL
+					AND (cardinality(w.event_types) = 0 OR $2 = ANY(w.event_types))
+					AND EXISTS (
+						SELECT 1 FROM accounts a
+						WHERE a.user_id = w.user_id AND (a.id = $3 OR a.id IN (
+							SELECT le.account_id FROM ledger_entries le WHERE le.transaction_id = $3
+						))
+					)
+				ON CONFLICT ON CONSTRAINT webhook_deliveries_endpoint_event_key DO NOTHING
+			`, e.Id, e.Type, e.AggregateId)
+			if err != nil {
+				return fmt.Errorf("error scheduling webhook deliveries of event %d: %w", e.Id, err)
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			scheduled += int(n)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return scheduled, nil
+}
+
+// selectWebhookDeliveries lists the columns of a WebhookDelivery, in the order
+// scanWebhookDelivery scans them
+const selectWebhookDeliveries = `d.id, d.endpoint_id, d.event_id, d.event_type, d.delivery_state, d.attempts, d.next_attempt_at,
+	d.last_attempt_at, d.last_status_code, d.last_error, d.created_at, d.delivered_at`
+
+func scanWebhookDelivery(row rowScanner, extra ...interface{}) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	var lastAttemptAt, deliveredAt sql.NullTime
+	var lastStatusCode sql.NullInt64
+	dest := []interface{}{&d.Id, &d.EndpointId, &d.EventId, &d.EventType, &d.State, &d.Attempts, &d.NextAttemptAt,
+		&lastAttemptAt, &lastStatusCode, &d.LastError, &d.CreatedAt, &deliveredAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	d.LastAttemptAt = nullTime(lastAttemptAt)
+	d.LastStatusCode = int(lastStatusCode.Int64)
+	d.DeliveredAt = nullTime(deliveredAt)
+	return &d, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// ClaimDueWebhookDeliveries claims up to limit pending deliveries that are due, oldest due
+// first, by pushing their next attempt back by lease. No other worker claims a delivery
+// while its attempt is in flight, and one whose worker dies before recording the outcome is
+// attempted again once the lease runs out. Deliveries to deleted endpoints are never due.
+func (w *WebhookRepository) ClaimDueWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueWebhookDelivery, error) {
+	rows, err := w.db.QueryContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 millisecond'
+		FROM (
+			SELECT pending.id, w.url, w.secret, e.position, e.aggregate_id, e.payload, e.created_at AS event_created_at
+			FROM webhook_deliveries pending
+			JOIN webhook_endpoints w ON w.id = pending.endpoint_id
+			JOIN ledger_events e ON e.id = pending.event_id
+			WHERE pending.delivery_state = 'pending' AND pending.next_attempt_at <= CURRENT_TIMESTAMP AND w.deleted_at IS NULL
+			ORDER BY pending.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF pending SKIP LOCKED
+		) due
+		WHERE d.id = due.id
+		RETURNING `+selectWebhookDeliveries+`, due.url, due.secret, due.position, due.aggregate_id, due.payload, due.event_created_at
+	`, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []DueWebhookDelivery
+	for rows.Next() {
+		var due DueWebhookDelivery
+		d, err := scanWebhookDelivery(rows, &due.Url, &due.Secret, &due.Event.Position, &due.Event.AggregateId, &due.Event.Payload, &due.Event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		due.WebhookDelivery = *d
+		due.Event.Id = d.EventId
+		due.Event.Type = d.EventType
+		deliveries = append(deliveries, due)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RecordWebhookAttempt records the outcome of an attempt at a pending delivery: it either
+// succeeded, is attempted again after attempt.RetryAfter, or is dead
+func (w *WebhookRepository) RecordWebhookAttempt(ctx context.Context, deliveryId int64, attempt WebhookAttempt) error {
+	state := WebhookDeliverySucceeded
+	if attempt.Error != "" {
+		state = WebhookDeliveryPending
+		if attempt.RetryAfter <= 0 {
+			state = WebhookDeliveryDead
+		}
+	}
+	_, err := w.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET attempts = attempts + 1,
+			last_attempt_at = CURRENT_TIMESTAMP,
+			last_status_code = $2,
+			last_error = $3,
+			delivery_state = $4,
+			next_attempt_at = CURRENT_TIMESTAMP + $5 * INTERVAL '1 millisecond',
+			delivered_at = CASE WHEN $4 = 'succeeded' THEN CURRENT_TIMESTAMP END
+		WHERE id = $1 AND delivery_state = 'pending'
+	`, deliveryId, sql.NullInt64{Int64: int64(attempt.StatusCode), Valid: attempt.StatusCode != 0}, attempt.Error, state, attempt.RetryAfter.Milliseconds())
+	if err != nil {
+		slog.ErrorContext(ctx, "error while recording webhook attempt", "delivery_id", deliveryId, "error", err)
+		return fmt.Errorf("error recording attempt at webhook delivery %d: %w", deliveryId, err)
+	}
+	return nil
+}
+
+// RedeliverWebhook schedules a delivery to one of a user's endpoints that succeeded or is
+// dead to be attempted again straight away, with a fresh set of attempts
+func (w *WebhookRepository) RedeliverWebhook(ctx context.Context, deliveryId int64, userId string) (*WebhookDelivery, error) {
+	var delivery *WebhookDelivery
+	err := runInTx(ctx, w.db, nil, DefaultRetryPolicy, func(tx *sql.Tx) error {
+		var owner string
+		d, err := scanWebhookDelivery(tx.QueryRowContext(ctx, `
+			SELECT `+selectWebhookDeliveries+`, w.user_id
+			FROM webhook_deliveries d
+			JOIN webhook_endpoints w ON w.id = d.endpoint_id
+			WHERE d.id = $1
+			FOR UPDATE OF d
+		`, deliveryId), &owner)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrWebhookDeliveryNotFound, deliveryId)
+		}
+		if err != nil {
+			return err
+		}
+		if owner != userId {
+			return fmt.Errorf("%w: webhook delivery %d is to endpoint %s, which is not user %s's", ErrWebhookEndpointNotOwned, deliveryId, d.EndpointId, userId)
+		}
+		if d.State == WebhookDeliveryPending {
+			return fmt.Errorf("%w: %d", ErrWebhookDeliveryPending, deliveryId)
+		}
+
+		delivery, err = scanWebhookDelivery(tx.QueryRowContext(ctx, `
+			UPDATE webhook_deliveries d
+			SET delivery_state = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, delivered_at = NULL
+			WHERE d.id = $1
+			RETURNING `+selectWebhookDeliveries, deliveryId))
+		return err
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "error while redelivering webhook", "delivery_id", deliveryId, "error", err)
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ListWebhookDeliveries lists the deliveries of one of a user's endpoints, newest first,
+// including those of a deleted endpoint. The returned cursor is 0 on the last page.
+func (w *WebhookRepository) ListWebhookDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]WebhookDelivery, int64, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultWebhookDeliveryPageSize
+	}
+	if _, err := readOwnedWebhookEndpoint(ctx, w.db, filter.UserId, filter.EndpointId, ""); err != nil {
+		return nil, 0, err
+	}
+
+	args := []interface{}{filter.EndpointId}
+	conditions := []string{"d.endpoint_id = $1"}
+	addCondition := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.State != "" {
+		addCondition("d.delivery_state = $%d", filter.State)
+	}
+	if filter.Cursor != 0 {
+		addCondition("d.id < $%d", filter.Cursor)
+	}
+	// One extra row tells whether there is another page
+	args = append(args, limit+1)
+	query := "SELECT " + selectWebhookDeliveries + " FROM webhook_deliveries d WHERE " + strings.Join(conditions, " AND ") +
+		fmt.Sprintf(" ORDER BY d.id DESC LIMIT $%d", len(args))
+
+	rows, err := w.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		slog.ErrorContext(ctx, "error while listing webhook deliveries", "error", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	var nextCursor int64
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		nextCursor = deliveries[limit-1].Id
+	}
+	return deliveries, nextCursor, nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestWebhookRepository(t *testing.T) {
+	db, container := test.SetupAndFillDatabaseContainer("seed_transactions_deposit_funds.sql")
+	defer func(container testcontainers.Container) {
+		err := test.TeardownDatabaseContainer(container)
+		if err != nil {
+			log.Fatalf("failed to close container down: %v\n", err)
+		}
+	}(container)
+	defer db.Close()
+
+	ctx := context.Background()
+	transactions := NewTransactionRepository(db, "txn_", "le_")
+	events := NewLedgerEventRepository(db)
+	webhooks := NewWebhookRepository(db, "whk_")
+
+	// Deliveries are matched to endpoints through the owners of the accounts
+	_, err := db.ExecContext(ctx, "UPDATE accounts SET user_id = 'usr_1' WHERE id IN ('acct_1', 'acct_2')")
+	assert.NoError(t, err)
+
+	everything, err := webhooks.CreateWebhookEndpoint(ctx, "usr_1", "https://example.com/all", nil)
+	assert.NoError(t, err)
+	assert.Contains(t, everything.Secret, webhookSecretPrefix)
+	balances, err := webhooks.CreateWebhookEndpoint(ctx, "usr_1", "https://example.com/balances", []string{EventBalanceChanged})
+	assert.NoError(t, err)
+	other, err := webhooks.CreateWebhookEndpoint(ctx, "usr_2", "https://example.com/other", nil)
+	assert.NoError(t, err)
+	_, err = webhooks.CreateWebhookEndpoint(ctx, "usr_9", "https://example.com", nil)
+	assert.True(t, errors.Is(err, ErrUserNotFound))
+
+	txnId, err := transactions.DepositFunds(ctx, usd(100), "usr_1", "acct_2", "acct_1", "")
+	assert.NoError(t, err)
+	var relayed []LedgerEvent
+	_, err = events.RelayBatch(ctx, "test", 100, func(batch []LedgerEvent) error {
+		relayed = append(relayed, batch...)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	t.Run("events are scheduled once to each endpoint that is sent them", func(t *testing.T) {
+		n, err := webhooks.ScheduleWebhookDeliveries(ctx, relayed)
+		assert.NoError(t, err)
+		// The transaction and both balance changes to one endpoint, the balance changes to the
+		// other, and nothing to usr_2 whose accounts were not touched
+		assert.Equal(t, 5, n)
+
+		n, err = webhooks.ScheduleWebhookDeliveries(ctx, relayed)
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	var claimed []DueWebhookDelivery
+	t.Run("due deliveries are claimed by one worker at a time", func(t *testing.T) {
+		claimed, err = webhooks.ClaimDueWebhookDeliveries(ctx, 10, time.Minute)
+		assert.NoError(t, err)
+		if !assert.Len(t, claimed, 5) {
+			return
+		}
+		perEndpoint := map[string]int{}
+		for _, d := range claimed {
+			perEndpoint[d.EndpointId]++
+			assert.Equal(t, WebhookDeliveryPending, d.State)
+			assert.Equal(t, d.EventType, d.Event.Type)
+			assert.NotEmpty(t, d.Event.Payload)
+			assert.NotEmpty(t, d.Secret)
+			if d.EventType == EventTransactionCreated {
+				assert.Equal(t, txnId, d.Event.AggregateId)
+			}
+		}
+		assert.Equal(t, map[string]int{everything.Id: 3, balances.Id: 2}, perEndpoint)
+
+		again, err := webhooks.ClaimDueWebhookDeliveries(ctx, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Empty(t, again)
+	})
+	if len(claimed) != 5 {
+		return
+	}
+
+	succeeded, retried, dead := claimed[0], claimed[1], claimed[2]
+	t.Run("attempts are recorded", func(t *testing.T) {
+		assert.NoError(t, webhooks.RecordWebhookAttempt(ctx, succeeded.Id, WebhookAttempt{StatusCode: 204}))
+		assert.NoError(t, webhooks.RecordWebhookAttempt(ctx, retried.Id, WebhookAttempt{StatusCode: 503, Error: "unavailable", RetryAfter: time.Hour}))
+		assert.NoError(t, webhooks.RecordWebhookAttempt(ctx, dead.Id, WebhookAttempt{Error: "connection refused"}))
+
+		states := map[int64]WebhookDelivery{}
+		for _, endpointId := range []string{everything.Id, balances.Id} {
+			got, _, err := webhooks.ListWebhookDeliveries(ctx, WebhookDeliveryFilter{EndpointId: endpointId, UserId: "usr_1"})
+			assert.NoError(t, err)
+			for _, d := range got {
+				states[d.Id] = d
+			}
+		}
+		assert.Equal(t, WebhookDeliverySucceeded, states[succeeded.Id].State)
+		assert.Equal(t, 204, states[succeeded.Id].LastStatusCode)
+		assert.NotNil(t, states[succeeded.Id].DeliveredAt)
+		assert.Equal(t, WebhookDeliveryPending, states[retried.Id].State)
+		assert.Equal(t, "unavailable", states[retried.Id].LastError)
+		assert.Equal(t, 1, states[retried.Id].Attempts)
+		assert.True(t, states[retried.Id].NextAttemptAt.After(time.Now().Add(30*time.Minute)))
+		assert.Equal(t, WebhookDeliveryDead, states[dead.Id].State)
+		assert.Zero(t, states[dead.Id].LastStatusCode)
+	})
+
+	t.Run("a delivery that is not pending can be redelivered", func(t *testing.T) {
+		_, err := webhooks.RedeliverWebhook(ctx, retried.Id, "usr_1")
+		assert.True(t, errors.Is(err, ErrWebhookDeliveryPending))
+		_, err = webhooks.RedeliverWebhook(ctx, 1<<40, "usr_1")
+		assert.True(t, errors.Is(err, ErrWebhookDeliveryNotFound))
+
+		d, err := webhooks.RedeliverWebhook(ctx, dead.Id, "usr_1")
+		assert.NoError(t, err)
+		assert.Equal(t, WebhookDeliveryPending, d.State)
+		assert.Zero(t, d.Attempts)
+		assert.Equal(t, "connection refused", d.LastError, "the last error is kept until the next attempt")
+
+		due, err := webhooks.ClaimDueWebhookDeliveries(ctx, 10, time.Minute)
+		assert.NoError(t, err)
+		if assert.Len(t, due, 1) {
+			assert.Equal(t, dead.Id, due[0].Id)
+		}
+		assert.NoError(t, webhooks.RecordWebhookAttempt(ctx, dead.Id, WebhookAttempt{StatusCode: 200}))
+	})
+
+	t.Run("deliveries are listed newest first", func(t *testing.T) {
+		first, cursor, err := webhooks.ListWebhookDeliveries(ctx, WebhookDeliveryFilter{EndpointId: everything.Id, UserId: "usr_1", Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, first, 2)
+		assert.NotZero(t, cursor)
+		rest, cursor, err := webhooks.ListWebhookDeliveries(ctx, WebhookDeliveryFilter{EndpointId: everything.Id, UserId: "usr_1", Limit: 2, Cursor: cursor})
+		assert.NoError(t, err)
+		assert.Len(t, rest, 1)
+		assert.Zero(t, cursor)
+		all := append(first, rest...)
+		for i := 1; i < len(all); i++ {
+			assert.Less(t, all[i].Id, all[i-1].Id)
+		}
+
+		pending, _, err := webhooks.ListWebhookDeliveries(ctx, WebhookDeliveryFilter{EndpointId: everything.Id, UserId: "usr_1", State: WebhookDeliveryPending})
+		assert.NoError(t, err)
+		for _, d := range pending {
+			assert.Equal(t, WebhookDeliveryPending, d.State)
+		}
+
+		none, _, err := webhooks.ListWebhookDeliveries(ctx, WebhookDeliveryFilter{EndpointId: other.Id, UserId: "usr_2"})
+		assert.NoError(t, err)
+		assert.Empty(t, none)
+	})
+
+	t.Run("another user's endpoint cannot be managed", func(t *testing.T) {
+		_, _, err := webhooks.ListWebhookDeliveries(ctx, WebhookDeliveryFilter{EndpointId: everything.Id, UserId: "usr_2"})
+		assert.True(t, errors.Is(err, ErrWebhookEndpointNotOwned))
+		_, _, err = webhooks.ListWebhookDeliveries(ctx, WebhookDeliveryFilter{EndpointId: "whk_missing", UserId: "usr_2"})
+		assert.True(t, errors.Is(err, ErrWebhookEndpointNotFound))
+
+		_, err = webhooks.RedeliverWebhook(ctx, dead.Id, "usr_2")
+		assert.True(t, errors.Is(err, ErrWebhookEndpointNotOwned))
+
+		_, err = webhooks.DeleteWebhookEndpoint(ctx, everything.Id, "usr_2")
+		assert.True(t, errors.Is(err, ErrWebhookEndpointNotOwned))
+		endpoints, err := webhooks.ListWebhookEndpoints(ctx, "usr_1")
+		assert.NoError(t, err)
+		assert.Len(t, endpoints, 2, "the endpoint is not deleted")
+	})
+
+	t.Run("a deleted endpoint is sent nothing", func(t *testing.T) {
+		_, err := webhooks.RedeliverWebhook(ctx, succeeded.Id, "usr_1")
+		assert.NoError(t, err)
+		_, err = webhooks.DeleteWebhookEndpoint(ctx, succeeded.EndpointId, "usr_1")
+		assert.NoError(t, err)
+		_, err = webhooks.DeleteWebhookEndpoint(ctx, succeeded.EndpointId, "usr_1")
+		assert.True(t, errors.Is(err, ErrWebhookEndpointNotFound))
+
+		due, err := webhooks.ClaimDueWebhookDeliveries(ctx, 10, time.Minute)
+		assert.NoError(t, err)
+		assert.Empty(t, due)
+
+		endpoints, err := webhooks.ListWebhookEndpoints(ctx, "usr_1")
+		assert.NoError(t, err)
+		assert.Len(t, endpoints, 1)
+		for _, e := range endpoints {
+			assert.NotEqual(t, succeeded.EndpointId, e.Id)
+			assert.Empty(t, e.Secret)
+		}
+	})
+}
@@ -3,17 +3,33 @@ package grpc
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"time"
 
 	pb "github.com/rasha-hantash/chariot-takehome/api/grpc/proto"
 	"github.com/rasha-hantash/chariot-takehome/api/grpc/repository"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/broadcast"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/currency"
 	lg "github.com/rasha-hantash/chariot-takehome/api/pkgs/logger"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/pagetoken"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type GrpcService struct {
-	UserRepo        *repository.UserRepository
-	AccountRepo     *repository.AccountRepository
-	TransactionRepo *repository.TransactionRepository
+	UserRepo          *repository.UserRepository
+	AccountRepo       *repository.AccountRepository
+	TransactionRepo   *repository.TransactionRepository
+	HoldRepo          *repository.HoldRepository
+	WebhookRepo       *repository.WebhookRepository
+	PaymentMethodRepo *repository.PaymentMethodRepository
+	// PageTokens signs the page tokens handed out by list RPCs
+	PageTokens *pagetoken.Codec
+	// Events is read by watch RPCs, which wait on EventWakeups for new events to be committed
+	Events       *repository.LedgerEventRepository
+	EventWakeups *broadcast.Broadcaster
 	pb.UnimplementedApiServiceServer
 }
 
@@ -22,42 +38,143 @@ func (g *GrpcService) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 	slog.InfoContext(ctx, "creating user")
 	
 	user := &repository.User{
-		Name:  req.Name,
-		Email: req.Email,
+		Name:     req.Name,
+		Email:    req.Email,
+		Currency: req.Currency,
 	}
 
 	res, err := g.UserRepo.CreateUser(ctx, user)
 	if err != nil {
 		return nil, err
 	}
-	return &pb.User{
-		Id:                 res.Id,
-		IntLedgerAccountId: res.IntLedgerAccountId.String,
-		ExtLedgerAccountId: res.ExtLedgerAccountId.String,
+	return userToProto(res), nil
+}
+
+func (g *GrpcService) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "getting user")
+
+	user, err := g.UserRepo.GetUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return userToProto(user), nil
+}
+
+func (g *GrpcService) GetUserByEmail(ctx context.Context, req *pb.GetUserByEmailRequest) (*pb.User, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("email", req.Email))
+	slog.InfoContext(ctx, "getting user by email")
+
+	user, err := g.UserRepo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	return userToProto(user), nil
+}
+
+func (g *GrpcService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.User, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("user_id", req.UserId), slog.String("name", req.Name))
+	slog.InfoContext(ctx, "updating user")
+
+	user, err := g.UserRepo.UpdateUser(ctx, req.UserId, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	return userToProto(user), nil
+}
+
+func (g *GrpcService) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("cursor", req.Cursor), slog.Int("page_size", int(req.Limit)))
+	slog.InfoContext(ctx, "listing users")
+
+	users, nextCursor, err := g.UserRepo.ListUsers(ctx, req.Cursor, int(req.Limit))
+	if err != nil {
+		return nil, err
+	}
+	pbUsers := make([]*pb.User, 0, len(users))
+	for i := range users {
+		pbUsers = append(pbUsers, userToProto(&users[i]))
+	}
+	return &pb.ListUsersResponse{
+		Users:      pbUsers,
+		NextCursor: nextCursor,
 	}, nil
 }
 
+func (g *GrpcService) DeactivateUser(ctx context.Context, req *pb.DeactivateUserRequest) (*pb.User, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "deactivating user")
+
+	user, err := g.UserRepo.DeactivateUser(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return userToProto(user), nil
+}
+
 func (g *GrpcService) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.Account, error) {
-	ctx = lg.AppendCtx(ctx, slog.String("account_state", req.AccountState), slog.String("account_type", req.AccountType))
+	ctx = lg.AppendCtx(ctx, slog.String("account_state", req.AccountState), slog.String("account_type", req.AccountType), slog.String("user_id", req.UserId))
 	slog.InfoContext(ctx, "creating account")
 	
-	account := &repository.Account{
-		AccountState: req.AccountState,
-		AccountType:  req.AccountType,
+	account, err := accountFromProto(req)
+	if err != nil {
+		return nil, err
 	}
 
 	id, err := g.AccountRepo.CreateAccount(ctx, account)
 	if err != nil {
 		return nil, err
 	}
-	return &pb.Account{Id: id}, nil
+	account.Id = id
+	return accountToProto(account), nil
+}
+
+func (g *GrpcService) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.Account, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("account_id", req.AccountId))
+	slog.InfoContext(ctx, "getting account")
+
+	account, err := g.AccountRepo.GetAccount(ctx, req.AccountId)
+	if err != nil {
+		return nil, err
+	}
+	return accountToProto(account), nil
+}
+
+func (g *GrpcService) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("user_id", req.UserId), slog.String("account_type", req.AccountType), slog.String("account_state", req.AccountState),
+		slog.String("cursor", req.Cursor), slog.Int("page_size", int(req.Limit)))
+	slog.InfoContext(ctx, "listing accounts")
+
+	accounts, nextCursor, err := g.AccountRepo.ListAccounts(ctx, repository.AccountFilter{
+		UserId:       req.UserId,
+		AccountType:  req.AccountType,
+		AccountState: req.AccountState,
+		Cursor:       req.Cursor,
+		Limit:        int(req.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pbAccounts := make([]*pb.Account, 0, len(accounts))
+	for i := range accounts {
+		pbAccounts = append(pbAccounts, accountToProto(&accounts[i]))
+	}
+	return &pb.ListAccountsResponse{
+		Accounts:   pbAccounts,
+		NextCursor: nextCursor,
+	}, nil
 }
 
 func (g *GrpcService) DepositFunds(ctx context.Context, req *pb.DepositFundsRequest) (*pb.Transaction, error) {
-	ctx = lg.AppendCtx(ctx, slog.Float64("amount", req.Amount), slog.String("user_id", req.UserId), slog.String("debit_account_id", req.DebitAccountId), slog.String("credit_account_id", req.CreditAccountId))
+	ctx = lg.AppendCtx(ctx, slog.Int64("amount", req.GetAmount().GetUnits()), slog.String("currency", req.GetAmount().GetCurrency()), slog.String("user_id", req.UserId), slog.String("debit_account_id", req.DebitAccountId), slog.String("credit_account_id", req.CreditAccountId), slog.String("payment_method_id", req.PaymentMethodId))
 	slog.InfoContext(ctx, "depositing funds")
 
-	id, err := g.TransactionRepo.DepositFunds(ctx, req.Amount, req.UserId, req.DebitAccountId, req.CreditAccountId)
+	amount, err := amountFromProto(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := g.TransactionRepo.DepositFunds(ctx, amount, req.UserId, req.DebitAccountId, req.CreditAccountId, req.PaymentMethodId)
 	if err != nil {
 		return nil, err
 	}
@@ -65,10 +182,15 @@ func (g *GrpcService) DepositFunds(ctx context.Context, req *pb.DepositFundsRequ
 }
 
 func (g *GrpcService) WithdrawFunds(ctx context.Context, req *pb.WithdrawFundsRequest) (*pb.Transaction, error) {
-	ctx = lg.AppendCtx(ctx, slog.Float64("amount", req.Amount), slog.String("user_id", req.UserId), slog.String("debit_account_id", req.DebitAccountId), slog.String("credit_account_id", req.CreditAccountId))
+	ctx = lg.AppendCtx(ctx, slog.Int64("amount", req.GetAmount().GetUnits()), slog.String("currency", req.GetAmount().GetCurrency()), slog.String("user_id", req.UserId), slog.String("debit_account_id", req.DebitAccountId), slog.String("credit_account_id", req.CreditAccountId), slog.String("payment_method_id", req.PaymentMethodId))
 	slog.InfoContext(ctx, "withdrawing funds")
 	
-	id, err := g.TransactionRepo.WithdrawFunds(ctx, req.Amount, req.UserId, req.DebitAccountId, req.CreditAccountId)
+	amount, err := amountFromProto(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := g.TransactionRepo.WithdrawFunds(ctx, amount, req.UserId, req.DebitAccountId, req.CreditAccountId, req.PaymentMethodId)
 	if err != nil {
 		return nil, err
 	}
@@ -76,10 +198,15 @@ func (g *GrpcService) WithdrawFunds(ctx context.Context, req *pb.WithdrawFundsRe
 }
 
 func (g *GrpcService) TransferFunds(ctx context.Context, req *pb.TransferFundsRequest) (*pb.Transaction, error) {
-	ctx = lg.AppendCtx(ctx, slog.Float64("amount", req.Amount), slog.String("user_id", req.UserId), slog.String("debit_account_id", req.DebitAccountId), slog.String("credit_account_id", req.CreditAccountId))
+	ctx = lg.AppendCtx(ctx, slog.Int64("amount", req.GetAmount().GetUnits()), slog.String("currency", req.GetAmount().GetCurrency()), slog.String("user_id", req.UserId), slog.String("debit_account_id", req.DebitAccountId), slog.String("credit_account_id", req.CreditAccountId))
 	slog.InfoContext(ctx, "transferring funds")
 	
-	id, err := g.TransactionRepo.TransferFunds(ctx, req.Amount, req.UserId, req.DebitAccountId, req.CreditAccountId)
+	amount, err := amountFromProto(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := g.TransactionRepo.TransferFunds(ctx, amount, req.UserId, req.DebitAccountId, req.CreditAccountId)
 	if err != nil {
 		return nil, err
 	}
@@ -87,45 +214,772 @@ func (g *GrpcService) TransferFunds(ctx context.Context, req *pb.TransferFundsRe
 }
 
 func (g *GrpcService) GetAccountBalance(ctx context.Context, req *pb.GetAccountBalanceRequest) (*pb.AccountBalance, error) {
-	ctx = lg.AppendCtx(ctx, slog.String("account_id", req.AccountId), slog.Time("timestamp", req.AtTime.AsTime()))
+	// An unset at_time means "now"; AsTime on a nil timestamp would otherwise be the Unix epoch
+	var atTime time.Time
+	if req.AtTime != nil {
+		atTime = req.AtTime.AsTime()
+	}
+	ctx = lg.AppendCtx(ctx, slog.String("account_id", req.AccountId), slog.Time("timestamp", atTime))
 	slog.InfoContext(ctx, "getting account balance")
 	
-	balance, err := g.AccountRepo.GetAccountBalance(ctx, req.AccountId)
+	balance, err := g.AccountRepo.GetAccountBalance(ctx, req.AccountId, atTime)
 	if err != nil {
 		return nil, err
 	}
-	if balance == 0 {
-		return &pb.AccountBalance{Balance: 0}, nil
+	return balanceToProto(balance), nil
+}
+
+func (g *GrpcService) GetTransaction(ctx context.Context, req *pb.GetTransactionRequest) (*pb.Transaction, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("transaction_id", req.TransactionId))
+	slog.InfoContext(ctx, "getting transaction")
+
+	txn, err := g.TransactionRepo.GetTransaction(ctx, req.TransactionId)
+	if err != nil {
+		return nil, err
 	}
-	return &pb.AccountBalance{Balance: float64(balance / 100)}, nil
+	return transactionToProto(*txn), nil
 }
 
 func (g *GrpcService) ListTransactions(ctx context.Context, req *pb.ListTransactionsRequest) (*pb.ListTransactionsResponse, error) {
-	ctx = lg.AppendCtx(ctx, slog.String("account_id", req.AccountId), slog.String("cursor", req.Cursor), slog.Int("page_size", int(req.Limit)))
+	ctx = lg.AppendCtx(ctx, slog.String("account_id", req.AccountId), slog.Bool("page_token", req.PageToken != ""), slog.Int("page_size", int(req.Limit)))
 	slog.InfoContext(ctx, "listing transactions")
 	
-	var filter repository.TransactionFilter
-
-	filter.AccountID = &req.AccountId
-	filter.Cursor = &req.Cursor
-	filter.Limit = nil // todo change this to req.PageSize
+	filter, err := transactionFilterFromProto(req)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint, err := transactionListFingerprint(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.PageToken != "" {
+		token, err := g.PageTokens.Decode(req.PageToken, fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		filter.Position = &repository.PagePosition{CreatedAt: token.CreatedAt, ID: token.ID}
+		filter.Backward = token.Backward
+	}
 
-	transactions, nextCursor, err := g.TransactionRepo.ListTransactions(ctx, &filter)
+	transactions, page, err := g.TransactionRepo.ListTransactions(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	var pbTransactions []*pb.Transaction
 	for _, t := range transactions {
-		pbTransactions = append(pbTransactions, &pb.Transaction{
-			Id:        t.Id,
-			Amount:    float64(t.Amount),
-			AccountId: t.AccountId,
-			Direction: t.Direction,
-		})
+		pbTransactions = append(pbTransactions, transactionToProto(t))
+	}
+
+	next, err := g.encodePageToken(fingerprint, page.Next, false)
+	if err != nil {
+		return nil, err
+	}
+	prev, err := g.encodePageToken(fingerprint, page.Prev, true)
+	if err != nil {
+		return nil, err
 	}
 
 	return &pb.ListTransactionsResponse{
-		Transactions: pbTransactions,
-		NextCursor:   nextCursor,
+		Transactions:  pbTransactions,
+		NextPageToken: next,
+		PrevPageToken: prev,
+	}, nil
+}
+
+func (g *GrpcService) CreateWebhookEndpoint(ctx context.Context, req *pb.CreateWebhookEndpointRequest) (*pb.WebhookEndpoint, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("user_id", req.UserId), slog.Any("event_types", req.EventTypes))
+	slog.InfoContext(ctx, "creating webhook endpoint")
+
+	endpoint, err := g.WebhookRepo.CreateWebhookEndpoint(ctx, req.UserId, req.Url, req.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+	return webhookEndpointToProto(endpoint), nil
+}
+
+func (g *GrpcService) ListWebhookEndpoints(ctx context.Context, req *pb.ListWebhookEndpointsRequest) (*pb.ListWebhookEndpointsResponse, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "listing webhook endpoints")
+
+	endpoints, err := g.WebhookRepo.ListWebhookEndpoints(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	pbEndpoints := make([]*pb.WebhookEndpoint, 0, len(endpoints))
+	for i := range endpoints {
+		pbEndpoints = append(pbEndpoints, webhookEndpointToProto(&endpoints[i]))
+	}
+	return &pb.ListWebhookEndpointsResponse{Endpoints: pbEndpoints}, nil
+}
+
+func (g *GrpcService) DeleteWebhookEndpoint(ctx context.Context, req *pb.DeleteWebhookEndpointRequest) (*pb.WebhookEndpoint, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("endpoint_id", req.EndpointId), slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "deleting webhook endpoint")
+
+	endpoint, err := g.WebhookRepo.DeleteWebhookEndpoint(ctx, req.EndpointId, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return webhookEndpointToProto(endpoint), nil
+}
+
+func (g *GrpcService) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("endpoint_id", req.EndpointId), slog.String("user_id", req.UserId), slog.String("delivery_state", req.DeliveryState),
+		slog.Int64("cursor", req.Cursor), slog.Int("page_size", int(req.Limit)))
+	slog.InfoContext(ctx, "listing webhook deliveries")
+
+	deliveries, nextCursor, err := g.WebhookRepo.ListWebhookDeliveries(ctx, repository.WebhookDeliveryFilter{
+		EndpointId: req.EndpointId,
+		UserId:     req.UserId,
+		State:      req.DeliveryState,
+		Cursor:     req.Cursor,
+		Limit:      int(req.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	pbDeliveries := make([]*pb.WebhookDelivery, 0, len(deliveries))
+	for i := range deliveries {
+		pbDeliveries = append(pbDeliveries, webhookDeliveryToProto(&deliveries[i]))
+	}
+	return &pb.ListWebhookDeliveriesResponse{
+		Deliveries: pbDeliveries,
+		NextCursor: nextCursor,
 	}, nil
 }
+
+func (g *GrpcService) RedeliverWebhook(ctx context.Context, req *pb.RedeliverWebhookRequest) (*pb.WebhookDelivery, error) {
+	ctx = lg.AppendCtx(ctx, slog.Int64("delivery_id", req.DeliveryId), slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "redelivering webhook")
+
+	delivery, err := g.WebhookRepo.RedeliverWebhook(ctx, req.DeliveryId, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return webhookDeliveryToProto(delivery), nil
+}
+
+func (g *GrpcService) AddPaymentMethod(ctx context.Context, req *pb.AddPaymentMethodRequest) (*pb.PaymentMethod, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("user_id", req.UserId), slog.String("method_type", req.MethodType))
+	slog.InfoContext(ctx, "adding payment method")
+
+	method := &repository.PaymentMethod{
+		UserId:        req.UserId,
+		MethodType:    req.MethodType,
+		AccountNumber: req.AccountNumber,
+		RoutingNumber: req.RoutingNumber,
+		CardNumber:    req.CardNumber,
+	}
+	if req.MethodType == repository.PaymentMethodCard {
+		expirationDate := time.Date(int(req.ExpirationYear), time.Month(req.ExpirationMonth), 1, 0, 0, 0, 0, time.UTC)
+		method.ExpirationDate = &expirationDate
+	}
+	added, err := g.PaymentMethodRepo.AddPaymentMethod(ctx, method)
+	if err != nil {
+		return nil, err
+	}
+	return paymentMethodToProto(added), nil
+}
+
+func (g *GrpcService) ListPaymentMethods(ctx context.Context, req *pb.ListPaymentMethodsRequest) (*pb.ListPaymentMethodsResponse, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "listing payment methods")
+
+	methods, err := g.PaymentMethodRepo.ListPaymentMethods(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	pbMethods := make([]*pb.PaymentMethod, 0, len(methods))
+	for i := range methods {
+		pbMethods = append(pbMethods, paymentMethodToProto(&methods[i]))
+	}
+	return &pb.ListPaymentMethodsResponse{PaymentMethods: pbMethods}, nil
+}
+
+func (g *GrpcService) VerifyPaymentMethod(ctx context.Context, req *pb.PaymentMethodRequest) (*pb.PaymentMethod, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("user_id", req.UserId), slog.String("payment_method_id", req.PaymentMethodId))
+	slog.InfoContext(ctx, "verifying payment method")
+
+	method, err := g.PaymentMethodRepo.VerifyPaymentMethod(ctx, req.UserId, req.PaymentMethodId)
+	if err != nil {
+		return nil, err
+	}
+	return paymentMethodToProto(method), nil
+}
+
+func (g *GrpcService) RemovePaymentMethod(ctx context.Context, req *pb.PaymentMethodRequest) (*pb.PaymentMethod, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("user_id", req.UserId), slog.String("payment_method_id", req.PaymentMethodId))
+	slog.InfoContext(ctx, "removing payment method")
+
+	method, err := g.PaymentMethodRepo.RemovePaymentMethod(ctx, req.UserId, req.PaymentMethodId)
+	if err != nil {
+		return nil, err
+	}
+	return paymentMethodToProto(method), nil
+}
+
+// watchBatchSize is the most events a watch reads from the outbox at once
+const watchBatchSize = 100
+
+// transactionEventTypes are the events streamed by WatchTransactions
+var transactionEventTypes = []string{repository.EventTransactionCreated, repository.EventTransactionReversed}
+
+// WatchTransactions replays the account's transaction events after the cursor and then
+// streams new ones as they are committed, until the client goes away
+func (g *GrpcService) WatchTransactions(req *pb.WatchTransactionsRequest, stream pb.ApiService_WatchTransactionsServer) error {
+	ctx := lg.AppendCtx(stream.Context(), slog.String("account_id", req.AccountId), slog.Int64("from_cursor", req.FromCursor))
+	slog.InfoContext(ctx, "watching transactions")
+
+	if _, err := g.AccountRepo.GetAccount(ctx, req.AccountId); err != nil {
+		return err
+	}
+	// Subscribe before the first read so that nothing committed after it is missed
+	wakeups, unsubscribe := g.EventWakeups.Subscribe()
+	defer unsubscribe()
+
+	cursor := req.FromCursor
+	if cursor == 0 {
+		latest, err := g.Events.LatestPosition(ctx)
+		if err != nil {
+			return err
+		}
+		cursor = latest
+	}
+
+	caughtUp := false
+	for {
+		// Reading up to a known position lets the cursor move past events about other accounts
+		latest, err := g.Events.LatestPosition(ctx)
+		if err != nil {
+			return err
+		}
+		events, err := g.Events.ReadAccountEvents(ctx, req.AccountId, transactionEventTypes, cursor, latest, watchBatchSize)
+		if err != nil {
+			return err
+		}
+		if err := g.sendTransactionEvents(ctx, stream, req.AccountId, events); err != nil {
+			return err
+		}
+		if len(events) == watchBatchSize {
+			cursor = events[len(events)-1].Position
+			continue
+		}
+		cursor = max(cursor, latest)
+
+		if !caughtUp {
+			caughtUp = true
+			if err := stream.Send(&pb.TransactionEvent{Cursor: cursor}); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-wakeups:
+		}
+	}
+}
+
+// sendTransactionEvents sends each event with its transaction as it is now
+func (g *GrpcService) sendTransactionEvents(ctx context.Context, stream pb.ApiService_WatchTransactionsServer, accountId string, events []repository.LedgerEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.AggregateId
+	}
+	limit := len(ids)
+	transactions, _, err := g.TransactionRepo.ListTransactions(ctx, &repository.TransactionFilter{AccountID: &accountId, TransactionIDs: ids, Limit: &limit})
+	if err != nil {
+		return err
+	}
+	byId := make(map[string]repository.Transaction, len(transactions))
+	for _, t := range transactions {
+		byId[t.Id] = t
+	}
+
+	for _, e := range events {
+		txn, ok := byId[e.AggregateId]
+		if !ok {
+			return fmt.Errorf("transaction %s of event %d not found", e.AggregateId, e.Id)
+		}
+		if err := stream.Send(&pb.TransactionEvent{Cursor: e.Position, Type: e.Type, Transaction: transactionToProto(txn)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchBalance sends the account's current balance and then sends it again every time it
+// changes, until the client goes away
+func (g *GrpcService) WatchBalance(req *pb.WatchBalanceRequest, stream pb.ApiService_WatchBalanceServer) error {
+	ctx := lg.AppendCtx(stream.Context(), slog.String("account_id", req.AccountId))
+	slog.InfoContext(ctx, "watching balance")
+
+	if _, err := g.AccountRepo.GetAccount(ctx, req.AccountId); err != nil {
+		return err
+	}
+	wakeups, unsubscribe := g.EventWakeups.Subscribe()
+	defer unsubscribe()
+
+	// The balance is read after the position, so it includes every change up to it
+	cursor, err := g.Events.LatestPosition(ctx)
+	if err != nil {
+		return err
+	}
+	for {
+		balance, err := g.AccountRepo.GetAccountBalance(ctx, req.AccountId, time.Time{})
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(balanceToProto(balance)); err != nil {
+			return err
+		}
+
+		// Wait until a committed event says the balance changed
+		for changed := false; !changed; {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-wakeups:
+			}
+			latest, err := g.Events.LatestPosition(ctx)
+			if err != nil {
+				return err
+			}
+			events, err := g.Events.ReadAccountEvents(ctx, req.AccountId, []string{repository.EventBalanceChanged}, cursor, latest, 1)
+			if err != nil {
+				return err
+			}
+			changed = len(events) > 0
+			cursor = max(cursor, latest)
+		}
+	}
+}
+
+func (g *GrpcService) CreateHold(ctx context.Context, req *pb.CreateHoldRequest) (*pb.Hold, error) {
+	ctx = lg.AppendCtx(ctx, slog.Int64("amount", req.GetAmount().GetUnits()), slog.String("currency", req.GetAmount().GetCurrency()), slog.String("user_id", req.UserId), slog.String("debit_account_id", req.DebitAccountId), slog.String("credit_account_id", req.CreditAccountId))
+	slog.InfoContext(ctx, "creating hold")
+
+	amount, err := amountFromProto(req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = req.ExpiresAt.AsTime()
+	}
+
+	hold, err := g.HoldRepo.CreateHold(ctx, amount, req.UserId, req.DebitAccountId, req.CreditAccountId, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return holdToProto(hold), nil
+}
+
+func (g *GrpcService) CaptureHold(ctx context.Context, req *pb.CaptureHoldRequest) (*pb.Hold, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("hold_id", req.HoldId), slog.Int64("amount", req.GetAmount().GetUnits()), slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "capturing hold")
+
+	// An unset amount captures the full hold
+	var amount *money.Money
+	if req.Amount != nil {
+		m, err := amountFromProto(req.Amount)
+		if err != nil {
+			return nil, err
+		}
+		amount = &m
+	}
+
+	hold, err := g.HoldRepo.CaptureHold(ctx, req.HoldId, amount, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return holdToProto(hold), nil
+}
+
+func (g *GrpcService) VoidHold(ctx context.Context, req *pb.VoidHoldRequest) (*pb.Hold, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("hold_id", req.HoldId), slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "voiding hold")
+
+	hold, err := g.HoldRepo.VoidHold(ctx, req.HoldId, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return holdToProto(hold), nil
+}
+
+func (g *GrpcService) ReverseTransaction(ctx context.Context, req *pb.ReverseTransactionRequest) (*pb.Transaction, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("transaction_id", req.TransactionId), slog.Int64("amount", req.GetAmount().GetUnits()), slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "reversing transaction")
+
+	// An unset amount reverses whatever has not been reversed yet
+	var amount *money.Money
+	if req.Amount != nil {
+		m, err := amountFromProto(req.Amount)
+		if err != nil {
+			return nil, err
+		}
+		amount = &m
+	}
+
+	id, err := g.TransactionRepo.ReverseTransaction(ctx, req.TransactionId, amount, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Transaction{Id: id, ReversesTransactionId: req.TransactionId}, nil
+}
+
+func (g *GrpcService) PostJournalEntry(ctx context.Context, req *pb.PostJournalEntryRequest) (*pb.Transaction, error) {
+	ctx = lg.AppendCtx(ctx, slog.Int("legs", len(req.Legs)), slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "posting journal entry")
+
+	legs := make([]repository.JournalLeg, 0, len(req.Legs))
+	for _, leg := range req.Legs {
+		amount, err := amountFromProto(leg.Amount)
+		if err != nil {
+			return nil, err
+		}
+		legs = append(legs, repository.JournalLeg{AccountId: leg.AccountId, Direction: leg.Direction, Amount: amount})
+	}
+
+	id, err := g.TransactionRepo.PostJournalEntry(ctx, legs, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.Transaction{Id: id}, nil
+}
+
+func (g *GrpcService) FreezeAccount(ctx context.Context, req *pb.AccountStateChangeRequest) (*pb.Account, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("account_id", req.AccountId), slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "freezing account")
+
+	account, err := g.AccountRepo.FreezeAccount(ctx, req.AccountId, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return accountToProto(account), nil
+}
+
+func (g *GrpcService) UnfreezeAccount(ctx context.Context, req *pb.AccountStateChangeRequest) (*pb.Account, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("account_id", req.AccountId), slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "unfreezing account")
+
+	account, err := g.AccountRepo.UnfreezeAccount(ctx, req.AccountId, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return accountToProto(account), nil
+}
+
+func (g *GrpcService) CloseAccount(ctx context.Context, req *pb.AccountStateChangeRequest) (*pb.Account, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("account_id", req.AccountId), slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "closing account")
+
+	account, err := g.AccountRepo.CloseAccount(ctx, req.AccountId, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return accountToProto(account), nil
+}
+
+func (g *GrpcService) SetOverdraftLimit(ctx context.Context, req *pb.SetOverdraftLimitRequest) (*pb.Account, error) {
+	ctx = lg.AppendCtx(ctx, slog.String("account_id", req.AccountId), slog.Int64("limit", req.GetLimit().GetUnits()), slog.Bool("unlimited", req.Unlimited), slog.String("user_id", req.UserId))
+	slog.InfoContext(ctx, "setting overdraft limit")
+
+	// An unlimited overdraft has no limit amount
+	var limit *money.Money
+	if !req.Unlimited {
+		m, err := amountFromProto(req.Limit)
+		if err != nil {
+			return nil, err
+		}
+		limit = &m
+	}
+
+	account, err := g.AccountRepo.SetOverdraftLimit(ctx, req.AccountId, limit, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+	return accountToProto(account), nil
+}
+
+// transactionFilterFromProto converts the filters of a list request, leaving unset ones nil
+func transactionFilterFromProto(req *pb.ListTransactionsRequest) (*repository.TransactionFilter, error) {
+	filter := &repository.TransactionFilter{
+		AccountID:   &req.AccountId,
+		Descending:  req.Descending,
+		IncludeLegs: req.IncludeLegs,
+	}
+	if req.Limit > 0 {
+		limit := int(req.Limit)
+		filter.Limit = &limit
+	}
+	if req.From != nil {
+		from := req.From.AsTime()
+		filter.CreatedFrom = &from
+	}
+	if req.To != nil {
+		to := req.To.AsTime()
+		filter.CreatedTo = &to
+	}
+	if req.Direction != "" {
+		filter.Direction = &req.Direction
+	}
+	if req.Status != "" {
+		filter.Status = &req.Status
+	}
+	if req.MinAmount != nil {
+		m, err := amountFromProto(req.MinAmount)
+		if err != nil {
+			return nil, err
+		}
+		filter.MinAmount = &m
+	}
+	if req.MaxAmount != nil {
+		m, err := amountFromProto(req.MaxAmount)
+		if err != nil {
+			return nil, err
+		}
+		filter.MaxAmount = &m
+	}
+	if req.CounterpartyAccountId != "" {
+		filter.CounterpartyAccountID = &req.CounterpartyAccountId
+	}
+	if req.CreatedBy != "" {
+		filter.CreatedBy = &req.CreatedBy
+	}
+	return filter, nil
+}
+
+// transactionListFingerprint identifies the filters and sort order of a list request, which
+// its page tokens are tied to. The page size and whether legs are included may change from
+// one page to the next.
+func transactionListFingerprint(req *pb.ListTransactionsRequest) (string, error) {
+	filters := proto.Clone(req).(*pb.ListTransactionsRequest)
+	filters.PageToken = ""
+	filters.Limit = 0
+	filters.IncludeLegs = false
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(filters)
+	if err != nil {
+		return "", fmt.Errorf("error fingerprinting list filters: %w", err)
+	}
+	return pagetoken.Fingerprint(b), nil
+}
+
+// encodePageToken returns the token for the page listed from a position, or an empty token
+// when there is no such page
+func (g *GrpcService) encodePageToken(filters string, position *repository.PagePosition, backward bool) (string, error) {
+	if position == nil {
+		return "", nil
+	}
+	return g.PageTokens.Encode(pagetoken.Token{Filters: filters, CreatedAt: position.CreatedAt, ID: position.ID, Backward: backward})
+}
+
+// amountFromProto converts a requested amount into an exact Money value, rejecting
+// unknown currencies and negative amounts
+func amountFromProto(m *pb.Money) (money.Money, error) {
+	if m == nil {
+		return money.Money{}, fmt.Errorf("%w: amount is required", money.ErrInvalidAmount)
+	}
+	return money.New(m.Units, m.Currency)
+}
+
+// accountFromProto converts a request for a new account. Without an overdraft limit a credit
+// account may go negative without limit, like the credit accounts that predate limits, and a
+// debit account may not go negative at all.
+func accountFromProto(req *pb.CreateAccountRequest) (*repository.Account, error) {
+	account := &repository.Account{
+		UserId:             req.UserId,
+		AccountState:       req.AccountState,
+		AccountType:        req.AccountType,
+		Currency:           req.Currency,
+		UnlimitedOverdraft: req.UnlimitedOverdraft || (req.OverdraftLimit == nil && req.AccountType == "credit"),
+	}
+	if account.UnlimitedOverdraft || req.OverdraftLimit == nil {
+		return account, nil
+	}
+
+	limit, err := amountFromProto(req.OverdraftLimit)
+	if err != nil {
+		return nil, err
+	}
+	code := req.Currency
+	if code == "" {
+		code = currency.Default
+	}
+	cur, err := currency.Lookup(code)
+	if err != nil {
+		return nil, err
+	}
+	if limit.Currency != cur.Code {
+		return nil, fmt.Errorf("%w: limit is in %s but the account is in %s", money.ErrCurrencyMismatch, limit.Currency, cur.Code)
+	}
+	account.OverdraftLimit = limit.Units
+	return account, nil
+}
+
+func moneyToProto(m money.Money) *pb.Money {
+	return &pb.Money{Units: m.Units, Currency: m.Currency}
+}
+
+func userToProto(u *repository.User) *pb.User {
+	return &pb.User{
+		Id:                 u.Id,
+		Name:               u.Name,
+		Email:              u.Email,
+		IntLedgerAccountId: u.IntLedgerAccountId.String,
+		ExtLedgerAccountId: u.ExtLedgerAccountId.String,
+		UserState:          u.UserState,
+		Currency:           u.Currency,
+		CreatedAt:          timestamppb.New(u.CreatedAt),
+		UpdatedAt:          timestamppb.New(u.UpdatedAt),
+	}
+}
+
+func accountToProto(a *repository.Account) *pb.Account {
+	account := &pb.Account{
+		Id:                 a.Id,
+		UserId:             a.UserId,
+		AccountType:        a.AccountType,
+		AccountState:       a.AccountState,
+		Currency:           a.Currency,
+		OverdraftLimit:     moneyToProto(money.Money{Units: a.OverdraftLimit, Currency: a.Currency}),
+		UnlimitedOverdraft: a.UnlimitedOverdraft,
+	}
+	// Accounts returned from state changes are not read back in full
+	if !a.CreatedAt.IsZero() {
+		account.CreatedAt = timestamppb.New(a.CreatedAt)
+	}
+	if a.Balance != nil {
+		account.Balance = moneyToProto(a.Balance.Amount)
+		account.Pending = moneyToProto(a.Balance.Pending)
+		account.Available = moneyToProto(a.Balance.Available)
+	}
+	return account
+}
+
+func balanceToProto(b *repository.Balance) *pb.AccountBalance {
+	return &pb.AccountBalance{
+		AccountId: b.AccountId,
+		Balance:   moneyToProto(b.Amount),
+		Pending:   moneyToProto(b.Pending),
+		Available: moneyToProto(b.Available),
+		AsOf:      timestamppb.New(b.AsOf),
+
+		OverdraftLimit:     moneyToProto(b.OverdraftLimit),
+		UnlimitedOverdraft: b.UnlimitedOverdraft,
+	}
+}
+
+func webhookEndpointToProto(e *repository.WebhookEndpoint) *pb.WebhookEndpoint {
+	endpoint := &pb.WebhookEndpoint{
+		Id:         e.Id,
+		UserId:     e.UserId,
+		Url:        e.Url,
+		EventTypes: e.EventTypes,
+		Secret:     e.Secret,
+		CreatedAt:  timestamppb.New(e.CreatedAt),
+	}
+	if e.DeletedAt != nil {
+		endpoint.DeletedAt = timestamppb.New(*e.DeletedAt)
+	}
+	return endpoint
+}
+
+func webhookDeliveryToProto(d *repository.WebhookDelivery) *pb.WebhookDelivery {
+	delivery := &pb.WebhookDelivery{
+		Id:             d.Id,
+		EndpointId:     d.EndpointId,
+		EventId:        d.EventId,
+		EventType:      d.EventType,
+		DeliveryState:  d.State,
+		Attempts:       int32(d.Attempts),
+		LastStatusCode: int32(d.LastStatusCode),
+		LastError:      d.LastError,
+		CreatedAt:      timestamppb.New(d.CreatedAt),
+	}
+	if d.State == repository.WebhookDeliveryPending {
+		delivery.NextAttemptAt = timestamppb.New(d.NextAttemptAt)
+	}
+	if d.LastAttemptAt != nil {
+		delivery.LastAttemptAt = timestamppb.New(*d.LastAttemptAt)
+	}
+	if d.DeliveredAt != nil {
+		delivery.DeliveredAt = timestamppb.New(*d.DeliveredAt)
+	}
+	return delivery
+}
+
+// paymentMethodToProto leaves out the account and card numbers but for their last four digits
+func paymentMethodToProto(m *repository.PaymentMethod) *pb.PaymentMethod {
+	method := &pb.PaymentMethod{
+		Id:            m.Id,
+		UserId:        m.UserId,
+		MethodType:    m.MethodType,
+		LastFour:      m.LastFour(),
+		RoutingNumber: m.RoutingNumber,
+		IsVerified:    m.IsVerified,
+		CreatedAt:     timestamppb.New(m.CreatedAt),
+		UpdatedAt:     timestamppb.New(m.UpdatedAt),
+	}
+	if m.ExpirationDate != nil {
+		method.ExpirationMonth = int32(m.ExpirationDate.Month())
+		method.ExpirationYear = int32(m.ExpirationDate.Year())
+	}
+	if m.RemovedAt != nil {
+		method.RemovedAt = timestamppb.New(*m.RemovedAt)
+	}
+	return method
+}
+
+func holdToProto(h *repository.Hold) *pb.Hold {
+	return &pb.Hold{
+		Id:              h.Id,
+		DebitAccountId:  h.DebitAccountId,
+		CreditAccountId: h.CreditAccountId,
+		Amount:          moneyToProto(h.Amount),
+		CapturedAmount:  moneyToProto(h.CapturedAmount),
+		Status:          h.Status,
+		ExpiresAt:       timestamppb.New(h.ExpiresAt),
+		TransactionId:   h.TransactionId,
+		CreatedAt:       timestamppb.New(h.CreatedAt),
+	}
+}
+
+func transactionToProto(t repository.Transaction) *pb.Transaction {
+	txn := &pb.Transaction{
+		Id:                    t.Id,
+		Amount:                moneyToProto(t.Amount),
+		AccountId:             t.AccountId,
+		Direction:             t.Direction,
+		Status:                t.Status,
+		ReversesTransactionId: t.ReversesTransactionId,
+		ReversedAmount:        moneyToProto(t.ReversedAmount),
+		CreatedAt:             timestamppb.New(t.CreatedAt),
+		CounterpartyAccountId: t.CounterpartyAccountId,
+		CreatedBy:             t.CreatedBy,
+		UpdatedAt:             timestamppb.New(t.UpdatedAt),
+		UpdatedBy:             t.UpdatedBy,
+		ReversalIds:           t.ReversalIds,
+		PaymentMethodId:       t.PaymentMethodId,
+	}
+	for _, leg := range t.Legs {
+		txn.Legs = append(txn.Legs, &pb.LedgerEntry{
+			Id:        leg.Id,
+			AccountId: leg.AccountId,
+			Direction: leg.Direction,
+			Amount:    moneyToProto(leg.Amount),
+		})
+	}
+	for _, v := range t.History {
+		txn.History = append(txn.History, &pb.TransactionVersion{
+			Status:         v.Status,
+			ReversedAmount: moneyToProto(v.ReversedAmount),
+			UpdatedAt:      timestamppb.New(v.UpdatedAt),
+			UpdatedBy:      v.UpdatedBy,
+		})
+	}
+	return txn
+}
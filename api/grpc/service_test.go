@@ -0,0 +1,108 @@
+package grpc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/rasha-hantash/chariot-takehome/api/grpc/proto"
+	"github.com/rasha-hantash/chariot-takehome/api/grpc/repository"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/pagetoken"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionListPageTokens(t *testing.T) {
+	g := &GrpcService{PageTokens: pagetoken.NewCodec([]byte("secret"))}
+	req := &pb.ListTransactionsRequest{AccountId: "acct_1", Direction: "credit", Limit: 10}
+	fingerprint, err := transactionListFingerprint(req)
+	assert.NoError(t, err)
+
+	position := &repository.PagePosition{CreatedAt: time.Now().UTC().Truncate(time.Microsecond), ID: "txn_1"}
+	token, err := g.encodePageToken(fingerprint, position, true)
+	assert.NoError(t, err)
+
+	// The page size, whether legs are included and the token itself may change from one
+	// page to the next
+	next := &pb.ListTransactionsRequest{AccountId: "acct_1", Direction: "credit", Limit: 25, IncludeLegs: true, PageToken: token}
+	nextFingerprint, err := transactionListFingerprint(next)
+	assert.NoError(t, err)
+	decoded, err := g.PageTokens.Decode(token, nextFingerprint)
+	assert.NoError(t, err)
+	assert.Equal(t, position.ID, decoded.ID)
+	assert.True(t, position.CreatedAt.Equal(decoded.CreatedAt))
+	assert.True(t, decoded.Backward)
+
+	// Changing a filter or the sort order invalidates the token
+	for _, changed := range []*pb.ListTransactionsRequest{
+		{AccountId: "acct_1", Direction: "debit", PageToken: token},
+		{AccountId: "acct_2", Direction: "credit", PageToken: token},
+		{AccountId: "acct_1", Direction: "credit", Descending: true, PageToken: token},
+	} {
+		changedFingerprint, err := transactionListFingerprint(changed)
+		assert.NoError(t, err)
+		_, err = g.PageTokens.Decode(token, changedFingerprint)
+		assert.True(t, errors.Is(err, pagetoken.ErrInvalid))
+	}
+
+	empty, err := g.encodePageToken(fingerprint, nil, false)
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestAccountFromProto(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           *pb.CreateAccountRequest
+		wantLimit     int64
+		wantUnlimited bool
+		wantErr       error
+	}{
+		{
+			name:          "credit account defaults to an unlimited overdraft",
+			req:           &pb.CreateAccountRequest{AccountType: "credit"},
+			wantUnlimited: true,
+		},
+		{
+			name: "debit account defaults to no overdraft",
+			req:  &pb.CreateAccountRequest{AccountType: "debit"},
+		},
+		{
+			name:      "credit account with a limit",
+			req:       &pb.CreateAccountRequest{AccountType: "credit", OverdraftLimit: &pb.Money{Units: 5000, Currency: "USD"}},
+			wantLimit: 5000,
+		},
+		{
+			name: "credit account with a zero limit",
+			req:  &pb.CreateAccountRequest{AccountType: "credit", OverdraftLimit: &pb.Money{Currency: "USD"}},
+		},
+		{
+			name:          "debit account with an unlimited overdraft",
+			req:           &pb.CreateAccountRequest{AccountType: "debit", UnlimitedOverdraft: true},
+			wantUnlimited: true,
+		},
+		{
+			name:      "limit in the account's currency",
+			req:       &pb.CreateAccountRequest{AccountType: "debit", Currency: "eur", OverdraftLimit: &pb.Money{Units: 100, Currency: "EUR"}},
+			wantLimit: 100,
+		},
+		{
+			name:    "limit in another currency",
+			req:     &pb.CreateAccountRequest{AccountType: "debit", OverdraftLimit: &pb.Money{Units: 100, Currency: "EUR"}},
+			wantErr: money.ErrCurrencyMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account, err := accountFromProto(tt.req)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantLimit, account.OverdraftLimit)
+			assert.Equal(t, tt.wantUnlimited, account.UnlimitedOverdraft)
+		})
+	}
+}
@@ -0,0 +1,504 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	pb "github.com/rasha-hantash/chariot-takehome/api/grpc/proto"
+	"github.com/rasha-hantash/chariot-takehome/api/grpc/repository"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/currency"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ID prefixes of the resources referenced by requests; these match the prefixes the
+// repositories are created with
+const (
+	userIDPrefix        = "usr_"
+	accountIDPrefix     = "acct_"
+	transactionIDPrefix = "txn_"
+	holdIDPrefix        = "hold_"
+	webhookIDPrefix     = "whk_"
+	paymentMethodPrefix = "pm_"
+)
+
+const (
+	// MaxAmountUnits bounds every amount in a request, in minor units
+	MaxAmountUnits = 1_000_000_000_000
+	// MaxPageSize bounds the number of items a list request may ask for
+	MaxPageSize          = 100
+	maxIdempotencyKeyLen = 255
+	maxNameLen           = 255
+	maxPageTokenLen      = 1024
+	maxURLLen            = 2048
+	// maxCardValidityYears bounds how far in the future a card may expire
+	maxCardValidityYears = 20
+)
+
+// ValidationUnaryServerInterceptor rejects malformed requests before they reach the service.
+// Every field of the request is checked and all violations are returned together as an
+// InvalidArgument status with a BadRequest detail listing them per field.
+func ValidationUnaryServerInterceptor() grpclib.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (interface{}, error) {
+		if v := validateRequest(req); len(v) > 0 {
+			return nil, v.err()
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ValidationStreamServerInterceptor is ValidationUnaryServerInterceptor for streaming RPCs:
+// every message received from the client is validated before the service sees it
+func ValidationStreamServerInterceptor() grpclib.StreamServerInterceptor {
+	return func(srv interface{}, ss grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+		return handler(srv, &validatingServerStream{ss})
+	}
+}
+
+type validatingServerStream struct {
+	grpclib.ServerStream
+}
+
+func (s *validatingServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if v := validateRequest(m); len(v) > 0 {
+		return v.err()
+	}
+	return nil
+}
+
+// validateRequest returns the field violations of a request message
+func validateRequest(req interface{}) violations {
+	var v violations
+	switch r := req.(type) {
+	case *pb.CreateUserRequest:
+		v.name("name", r.Name)
+		v.email("email", r.Email)
+		v.optionalCurrency("currency", r.Currency)
+	case *pb.GetUserRequest:
+		v.id("user_id", r.UserId, userIDPrefix)
+	case *pb.GetUserByEmailRequest:
+		v.email("email", r.Email)
+	case *pb.UpdateUserRequest:
+		v.id("user_id", r.UserId, userIDPrefix)
+		v.name("name", r.Name)
+	case *pb.ListUsersRequest:
+		if r.Cursor != "" {
+			v.id("cursor", r.Cursor, userIDPrefix)
+		}
+		v.pageSize("limit", r.Limit)
+	case *pb.DeactivateUserRequest:
+		v.id("user_id", r.UserId, userIDPrefix)
+	case *pb.CreateAccountRequest:
+		v.oneOf("account_type", r.AccountType, "debit", "credit")
+		if r.AccountState != "" {
+			v.oneOf("account_state", r.AccountState, "open", "frozen", "closed")
+		}
+		v.optionalCurrency("currency", r.Currency)
+		if r.UserId != "" {
+			v.id("user_id", r.UserId, userIDPrefix)
+		}
+		if r.OverdraftLimit != nil && !r.UnlimitedOverdraft {
+			v.amountBound("overdraft_limit", r.OverdraftLimit)
+		}
+	case *pb.GetAccountRequest:
+		v.id("account_id", r.AccountId, accountIDPrefix)
+	case *pb.ListAccountsRequest:
+		if r.UserId != "" {
+			v.id("user_id", r.UserId, userIDPrefix)
+		}
+		if r.AccountType != "" {
+			v.oneOf("account_type", r.AccountType, "debit", "credit")
+		}
+		if r.AccountState != "" {
+			v.oneOf("account_state", r.AccountState, "open", "frozen", "closed")
+		}
+		if r.Cursor != "" {
+			v.id("cursor", r.Cursor, accountIDPrefix)
+		}
+		v.pageSize("limit", r.Limit)
+	case *pb.DepositFundsRequest:
+		v.posting(r.Amount, r.UserId, r.DebitAccountId, r.CreditAccountId, r.IdempotencyKey)
+		if r.PaymentMethodId != "" {
+			v.id("payment_method_id", r.PaymentMethodId, paymentMethodPrefix)
+		}
+	case *pb.WithdrawFundsRequest:
+		v.posting(r.Amount, r.UserId, r.DebitAccountId, r.CreditAccountId, r.IdempotencyKey)
+		if r.PaymentMethodId != "" {
+			v.id("payment_method_id", r.PaymentMethodId, paymentMethodPrefix)
+		}
+	case *pb.TransferFundsRequest:
+		v.posting(r.Amount, r.UserId, r.DebitAccountId, r.CreditAccountId, r.IdempotencyKey)
+	case *pb.CreateHoldRequest:
+		v.posting(r.Amount, r.UserId, r.DebitAccountId, r.CreditAccountId, r.IdempotencyKey)
+	case *pb.GetTransactionRequest:
+		v.id("transaction_id", r.TransactionId, transactionIDPrefix)
+	case *pb.ListTransactionsRequest:
+		v.id("account_id", r.AccountId, accountIDPrefix)
+		if len(r.PageToken) > maxPageTokenLen {
+			v.add("page_token", "must be at most %d characters", maxPageTokenLen)
+		}
+		v.pageSize("limit", r.Limit)
+		if r.From != nil && r.To != nil && !r.From.AsTime().Before(r.To.AsTime()) {
+			v.add("to", "must be after from")
+		}
+		if r.Direction != "" {
+			v.oneOf("direction", r.Direction, "debit", "credit")
+		}
+		if r.Status != "" {
+			v.oneOf("status", r.Status, "success", "reversed", "partially_reversed")
+		}
+		if r.MinAmount != nil {
+			v.amountBound("min_amount", r.MinAmount)
+		}
+		if r.MaxAmount != nil {
+			v.amountBound("max_amount", r.MaxAmount)
+		}
+		if r.MinAmount != nil && r.MaxAmount != nil {
+			if r.MinAmount.Currency != r.MaxAmount.Currency {
+				v.add("max_amount.currency", "must match min_amount.currency")
+			} else if r.MinAmount.Units > r.MaxAmount.Units {
+				v.add("max_amount.units", "must not be less than min_amount.units")
+			}
+		}
+		if r.CounterpartyAccountId != "" {
+			v.id("counterparty_account_id", r.CounterpartyAccountId, accountIDPrefix)
+		}
+		if r.CreatedBy != "" {
+			v.id("created_by", r.CreatedBy, userIDPrefix)
+		}
+	case *pb.GetAccountBalanceRequest:
+		v.id("account_id", r.AccountId, accountIDPrefix)
+	case *pb.CaptureHoldRequest:
+		v.id("hold_id", r.HoldId, holdIDPrefix)
+		if r.Amount != nil {
+			v.amount("amount", r.Amount)
+		}
+		v.id("user_id", r.UserId, userIDPrefix)
+		v.idempotencyKey("idempotency_key", r.IdempotencyKey)
+	case *pb.VoidHoldRequest:
+		v.id("hold_id", r.HoldId, holdIDPrefix)
+		v.id("user_id", r.UserId, userIDPrefix)
+	case *pb.ReverseTransactionRequest:
+		v.id("transaction_id", r.TransactionId, transactionIDPrefix)
+		if r.Amount != nil {
+			v.amount("amount", r.Amount)
+		}
+		v.id("user_id", r.UserId, userIDPrefix)
+		v.idempotencyKey("idempotency_key", r.IdempotencyKey)
+	case *pb.PostJournalEntryRequest:
+		if len(r.Legs) < 2 {
+			v.add("legs", "must have at least two legs")
+		}
+		for i, leg := range r.Legs {
+			field := fmt.Sprintf("legs[%d]", i)
+			v.id(field+".account_id", leg.AccountId, accountIDPrefix)
+			v.oneOf(field+".direction", leg.Direction, "debit", "credit")
+			v.amount(field+".amount", leg.Amount)
+		}
+		v.id("user_id", r.UserId, userIDPrefix)
+		v.idempotencyKey("idempotency_key", r.IdempotencyKey)
+	case *pb.AccountStateChangeRequest:
+		v.id("account_id", r.AccountId, accountIDPrefix)
+		v.id("user_id", r.UserId, userIDPrefix)
+	case *pb.WatchTransactionsRequest:
+		v.id("account_id", r.AccountId, accountIDPrefix)
+		if r.FromCursor < 0 {
+			v.add("from_cursor", "must not be negative")
+		}
+	case *pb.WatchBalanceRequest:
+		v.id("account_id", r.AccountId, accountIDPrefix)
+	case *pb.CreateWebhookEndpointRequest:
+		v.id("user_id", r.UserId, userIDPrefix)
+		v.url("url", r.Url)
+		for i, eventType := range r.EventTypes {
+			v.oneOf(fmt.Sprintf("event_types[%d]", i), eventType, repository.EventTypes...)
+		}
+	case *pb.ListWebhookEndpointsRequest:
+		v.id("user_id", r.UserId, userIDPrefix)
+	case *pb.DeleteWebhookEndpointRequest:
+		v.id("endpoint_id", r.EndpointId, webhookIDPrefix)
+		v.id("user_id", r.UserId, userIDPrefix)
+	case *pb.ListWebhookDeliveriesRequest:
+		v.id("endpoint_id", r.EndpointId, webhookIDPrefix)
+		if r.DeliveryState != "" {
+			v.oneOf("delivery_state", r.DeliveryState, repository.WebhookDeliveryPending, repository.WebhookDeliverySucceeded, repository.WebhookDeliveryDead)
+		}
+		if r.Cursor < 0 {
+			v.add("cursor", "must not be negative")
+		}
+		v.pageSize("limit", r.Limit)
+		v.id("user_id", r.UserId, userIDPrefix)
+	case *pb.RedeliverWebhookRequest:
+		if r.DeliveryId <= 0 {
+			v.add("delivery_id", "must be positive")
+		}
+		v.id("user_id", r.UserId, userIDPrefix)
+	case *pb.AddPaymentMethodRequest:
+		v.id("user_id", r.UserId, userIDPrefix)
+		v.paymentMethod(r, time.Now())
+	case *pb.ListPaymentMethodsRequest:
+		v.id("user_id", r.UserId, userIDPrefix)
+	case *pb.PaymentMethodRequest:
+		v.id("payment_method_id", r.PaymentMethodId, paymentMethodPrefix)
+		v.id("user_id", r.UserId, userIDPrefix)
+	case *pb.SetOverdraftLimitRequest:
+		v.id("account_id", r.AccountId, accountIDPrefix)
+		if !r.Unlimited {
+			v.limit("limit", r.Limit)
+		}
+		v.id("user_id", r.UserId, userIDPrefix)
+	}
+	return v
+}
+
+// violations collects what is wrong with a request, field by field
+type violations []*errdetails.BadRequest_FieldViolation
+
+func (v *violations) add(field, format string, args ...interface{}) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: field, Description: fmt.Sprintf(format, args...)})
+}
+
+// err returns the violations as an InvalidArgument status error
+func (v violations) err() error {
+	descriptions := make([]string, len(v))
+	for i, fv := range v {
+		descriptions[i] = fv.Field + ": " + fv.Description
+	}
+	st, err := status.New(codes.InvalidArgument, "invalid request: "+strings.Join(descriptions, "; ")).WithDetails(
+		&errdetails.ErrorInfo{Reason: "INVALID_REQUEST", Domain: ErrorDomain},
+		&errdetails.BadRequest{FieldViolations: v},
+	)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "invalid request")
+	}
+	return st.Err()
+}
+
+// posting checks the fields shared by requests that move money between two accounts
+func (v *violations) posting(amount *pb.Money, userId, debitAccountId, creditAccountId, idempotencyKey string) {
+	v.amount("amount", amount)
+	v.id("user_id", userId, userIDPrefix)
+	v.id("debit_account_id", debitAccountId, accountIDPrefix)
+	v.id("credit_account_id", creditAccountId, accountIDPrefix)
+	if debitAccountId != "" && debitAccountId == creditAccountId {
+		v.add("credit_account_id", "must differ from debit_account_id")
+	}
+	v.idempotencyKey("idempotency_key", idempotencyKey)
+}
+
+// paymentMethod checks that a new payment method has the details of its type, and none of
+// another type's: an ACH payment method needs an account number and a valid routing number,
+// and a card a valid card number and an expiration date that has not passed at now
+func (v *violations) paymentMethod(r *pb.AddPaymentMethodRequest, now time.Time) {
+	switch r.MethodType {
+	case repository.PaymentMethodACH:
+		v.digits("account_number", r.AccountNumber, 4, 17)
+		if v.digits("routing_number", r.RoutingNumber, 9, 9) && !validRoutingNumber(r.RoutingNumber) {
+			v.add("routing_number", "must be a valid ABA routing number")
+		}
+		if r.CardNumber != "" {
+			v.add("card_number", "must be empty for an ACH payment method")
+		}
+		if r.ExpirationMonth != 0 || r.ExpirationYear != 0 {
+			v.add("expiration_month", "must be empty for an ACH payment method")
+		}
+	case repository.PaymentMethodCard:
+		if v.digits("card_number", r.CardNumber, 12, 19) && !luhn(r.CardNumber) {
+			v.add("card_number", "must be a valid card number")
+		}
+		now = now.UTC()
+		switch {
+		case r.ExpirationMonth < 1 || r.ExpirationMonth > 12:
+			v.add("expiration_month", "must be between 1 and 12")
+		case int(r.ExpirationYear) > now.Year()+maxCardValidityYears:
+			v.add("expiration_year", "must be at most %d years away", maxCardValidityYears)
+		case int(r.ExpirationYear) < now.Year() || (int(r.ExpirationYear) == now.Year() && time.Month(r.ExpirationMonth) < now.Month()):
+			v.add("expiration_year", "card has expired")
+		}
+		if r.AccountNumber != "" {
+			v.add("account_number", "must be empty for a card")
+		}
+		if r.RoutingNumber != "" {
+			v.add("routing_number", "must be empty for a card")
+		}
+	default:
+		v.oneOf("method_type", r.MethodType, repository.PaymentMethodACH, repository.PaymentMethodCard)
+	}
+}
+
+// digits checks that a required number is between min and max digits long and reports
+// whether it is
+func (v *violations) digits(field, value string, min, max int) bool {
+	if value == "" {
+		v.add(field, "is required")
+		return false
+	}
+	if len(value) < min || len(value) > max || strings.Trim(value, "0123456789") != "" {
+		if min == max {
+			v.add(field, "must be %d digits", min)
+		} else {
+			v.add(field, "must be between %d and %d digits", min, max)
+		}
+		return false
+	}
+	return true
+}
+
+// validRoutingNumber checks the checksum digit of a nine digit ABA routing number
+func validRoutingNumber(number string) bool {
+	weights := [3]int{3, 7, 1}
+	sum := 0
+	for i, c := range number {
+		sum += weights[i%3] * int(c-'0')
+	}
+	return sum%10 == 0
+}
+
+// luhn checks the check digit of a card number with the Luhn algorithm
+func luhn(number string) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// id checks that a required ID is well formed and refers to the expected kind of resource
+func (v *violations) id(field, value, prefix string) {
+	switch {
+	case value == "":
+		v.add(field, "is required")
+	case !strings.HasPrefix(value, prefix) || !identifier.ID(value).Validate():
+		v.add(field, "must be a valid %s ID", prefix)
+	}
+}
+
+// amount checks that a required amount is positive, bounded and in a supported currency
+func (v *violations) amount(field string, m *pb.Money) {
+	if m == nil {
+		v.add(field, "is required")
+		return
+	}
+	if m.Units <= 0 || m.Units > MaxAmountUnits {
+		v.add(field+".units", "must be between 1 and %d", int64(MaxAmountUnits))
+	}
+	v.currency(field+".currency", m.Currency)
+}
+
+// limit checks an overdraft limit, which unlike an amount may be zero
+func (v *violations) limit(field string, m *pb.Money) {
+	if m == nil {
+		v.add(field, "is required unless unlimited is set")
+		return
+	}
+	v.amountBound(field, m)
+}
+
+// amountBound checks an amount that may be zero, such as a limit or a filter bound
+func (v *violations) amountBound(field string, m *pb.Money) {
+	if m.Units < 0 || m.Units > MaxAmountUnits {
+		v.add(field+".units", "must be between 0 and %d", int64(MaxAmountUnits))
+	}
+	v.currency(field+".currency", m.Currency)
+}
+
+func (v *violations) currency(field, code string) {
+	if code == "" {
+		v.add(field, "is required")
+		return
+	}
+	v.optionalCurrency(field, code)
+}
+
+func (v *violations) optionalCurrency(field, code string) {
+	if code == "" {
+		return
+	}
+	if _, err := currency.Lookup(code); err != nil {
+		v.add(field, "must be a supported ISO 4217 currency code")
+	}
+}
+
+func (v *violations) oneOf(field, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	if value == "" {
+		v.add(field, "is required")
+		return
+	}
+	v.add(field, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+func (v *violations) email(field, value string) {
+	if value == "" {
+		v.add(field, "is required")
+		return
+	}
+	// ParseAddress also accepts display names like "Jane <jane@example.com>"; only a bare
+	// address is allowed
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		v.add(field, "must be a valid email address")
+	}
+}
+
+// url checks a URL that the service will make requests to
+func (v *violations) url(field, value string) {
+	if value == "" {
+		v.add(field, "is required")
+		return
+	}
+	if len(value) > maxURLLen {
+		v.add(field, "must be at most %d characters", maxURLLen)
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(field, "must be an absolute http or https URL")
+	}
+}
+
+func (v *violations) name(field, value string) {
+	switch {
+	case strings.TrimSpace(value) == "":
+		v.add(field, "is required")
+	case len(value) > maxNameLen:
+		v.add(field, "must be at most %d characters", maxNameLen)
+	}
+}
+
+// pageSize checks the number of items a list request asks for, where zero means the default
+func (v *violations) pageSize(field string, limit int32) {
+	if limit < 0 || limit > MaxPageSize {
+		v.add(field, "must be between 0 and %d", MaxPageSize)
+	}
+}
+
+// idempotencyKey checks an optional idempotency key
+func (v *violations) idempotencyKey(field, value string) {
+	if len(value) > maxIdempotencyKeyLen {
+		v.add(field, "must be at most %d characters", maxIdempotencyKeyLen)
+	}
+}
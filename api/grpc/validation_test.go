@@ -0,0 +1,315 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/rasha-hantash/chariot-takehome/api/grpc/proto"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/identifier"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestValidateRequest(t *testing.T) {
+	userId := string(identifier.ID(userIDPrefix).New())
+	acct1 := string(identifier.ID(accountIDPrefix).New())
+	acct2 := string(identifier.ID(accountIDPrefix).New())
+	holdId := string(identifier.ID(holdIDPrefix).New())
+	paymentMethodId := string(identifier.ID(paymentMethodPrefix).New())
+	endpointId := string(identifier.ID(webhookIDPrefix).New())
+	nextYear := int32(time.Now().Year() + 1)
+	usd := func(units int64) *pb.Money { return &pb.Money{Units: units, Currency: "USD"} }
+
+	tests := []struct {
+		name       string
+		req        interface{}
+		wantFields []string
+	}{
+		{
+			name: "valid deposit",
+			req:  &pb.DepositFundsRequest{Amount: usd(100), UserId: userId, DebitAccountId: acct1, CreditAccountId: acct2},
+		},
+		{
+			name:       "zero amount",
+			req:        &pb.DepositFundsRequest{Amount: usd(0), UserId: userId, DebitAccountId: acct1, CreditAccountId: acct2},
+			wantFields: []string{"amount.units"},
+		},
+		{
+			name:       "negative amount",
+			req:        &pb.TransferFundsRequest{Amount: usd(-5), UserId: userId, DebitAccountId: acct1, CreditAccountId: acct2},
+			wantFields: []string{"amount.units"},
+		},
+		{
+			name:       "amount above the maximum",
+			req:        &pb.WithdrawFundsRequest{Amount: usd(MaxAmountUnits + 1), UserId: userId, DebitAccountId: acct1, CreditAccountId: acct2},
+			wantFields: []string{"amount.units"},
+		},
+		{
+			name:       "unknown currency",
+			req:        &pb.CreateHoldRequest{Amount: &pb.Money{Units: 100, Currency: "XYZ"}, UserId: userId, DebitAccountId: acct1, CreditAccountId: acct2},
+			wantFields: []string{"amount.currency"},
+		},
+		{
+			name:       "same account on both sides",
+			req:        &pb.DepositFundsRequest{Amount: usd(100), UserId: userId, DebitAccountId: acct1, CreditAccountId: acct1},
+			wantFields: []string{"credit_account_id"},
+		},
+		{
+			name:       "every field invalid",
+			req:        &pb.DepositFundsRequest{DebitAccountId: "acct_1", CreditAccountId: holdId},
+			wantFields: []string{"amount", "user_id", "debit_account_id", "credit_account_id"},
+		},
+		{
+			name:       "user ID where an account ID belongs",
+			req:        &pb.GetAccountBalanceRequest{AccountId: userId},
+			wantFields: []string{"account_id"},
+		},
+		{
+			name: "valid user",
+			req:  &pb.CreateUserRequest{Name: "Jane Doe", Email: "jane@example.com"},
+		},
+		{
+			name:       "malformed email and blank name",
+			req:        &pb.CreateUserRequest{Name: "  ", Email: "Jane <jane@example.com>"},
+			wantFields: []string{"name", "email"},
+		},
+		{
+			name:       "unknown account type and state",
+			req:        &pb.CreateAccountRequest{AccountType: "savings", AccountState: "active"},
+			wantFields: []string{"account_type", "account_state"},
+		},
+		{
+			name: "account state defaults to open",
+			req:  &pb.CreateAccountRequest{AccountType: "debit"},
+		},
+		{
+			name:       "negative overdraft limit",
+			req:        &pb.CreateAccountRequest{AccountType: "debit", OverdraftLimit: &pb.Money{Units: -1, Currency: "USD"}},
+			wantFields: []string{"overdraft_limit.units"},
+		},
+		{
+			name: "unlimited overdraft ignores the limit",
+			req:  &pb.CreateAccountRequest{AccountType: "credit", OverdraftLimit: &pb.Money{Units: -1}, UnlimitedOverdraft: true},
+		},
+		{
+			name: "full capture leaves the amount unset",
+			req:  &pb.CaptureHoldRequest{HoldId: holdId, UserId: userId},
+		},
+		{
+			name:       "page size above the maximum",
+			req:        &pb.ListTransactionsRequest{AccountId: acct1, Limit: MaxPageSize + 1},
+			wantFields: []string{"limit"},
+		},
+		{
+			name:       "user lookup by malformed email",
+			req:        &pb.GetUserByEmailRequest{Email: "not-an-email"},
+			wantFields: []string{"email"},
+		},
+		{
+			name:       "user update without a name",
+			req:        &pb.UpdateUserRequest{UserId: userId},
+			wantFields: []string{"name"},
+		},
+		{
+			name:       "user list with an account cursor",
+			req:        &pb.ListUsersRequest{Cursor: acct1},
+			wantFields: []string{"cursor"},
+		},
+		{
+			name: "transaction filters",
+			req: &pb.ListTransactionsRequest{AccountId: acct1, Direction: "credit", Status: "reversed",
+				MinAmount: usd(0), MaxAmount: usd(500), CounterpartyAccountId: acct2, CreatedBy: userId, Descending: true},
+		},
+		{
+			name: "get transaction",
+			req:  &pb.GetTransactionRequest{TransactionId: string(identifier.ID(transactionIDPrefix).New())},
+		},
+		{
+			name:       "get transaction by account ID",
+			req:        &pb.GetTransactionRequest{TransactionId: acct1},
+			wantFields: []string{"transaction_id"},
+		},
+		{
+			name:       "oversized page token",
+			req:        &pb.ListTransactionsRequest{AccountId: acct1, PageToken: strings.Repeat("a", 1025)},
+			wantFields: []string{"page_token"},
+		},
+		{
+			name: "inverted transaction filters",
+			req: &pb.ListTransactionsRequest{AccountId: acct1, Direction: "in", MinAmount: usd(500), MaxAmount: usd(100),
+				From: timestamppb.New(time.Now()), To: timestamppb.New(time.Now().Add(-time.Hour))},
+			wantFields: []string{"to", "direction", "max_amount.units"},
+		},
+		{
+			name: "zero overdraft limit",
+			req:  &pb.SetOverdraftLimitRequest{AccountId: acct1, Limit: usd(0), UserId: userId},
+		},
+		{
+			name: "unlimited overdraft needs no limit",
+			req:  &pb.SetOverdraftLimitRequest{AccountId: acct1, Unlimited: true, UserId: userId},
+		},
+		{
+			name: "journal entry legs are checked individually",
+			req: &pb.PostJournalEntryRequest{UserId: userId, Legs: []*pb.JournalLeg{
+				{AccountId: acct1, Direction: "debit", Amount: usd(100)},
+				{AccountId: acct2, Direction: "sideways", Amount: usd(100)},
+			}},
+			wantFields: []string{"legs[1].direction"},
+		},
+		{
+			name: "watch transactions from a cursor",
+			req:  &pb.WatchTransactionsRequest{AccountId: acct1, FromCursor: 42},
+		},
+		{
+			name:       "watch transactions from a negative cursor",
+			req:        &pb.WatchTransactionsRequest{AccountId: userId, FromCursor: -1},
+			wantFields: []string{"account_id", "from_cursor"},
+		},
+		{
+			name: "webhook endpoint for every event type",
+			req:  &pb.CreateWebhookEndpointRequest{UserId: userId, Url: "https://example.com/hooks"},
+		},
+		{
+			name:       "webhook endpoint with a relative URL and an unknown event type",
+			req:        &pb.CreateWebhookEndpointRequest{UserId: userId, Url: "/hooks", EventTypes: []string{"transaction.created", "transfer.completed"}},
+			wantFields: []string{"url", "event_types[1]"},
+		},
+		{
+			name:       "webhook endpoint with a non-http URL",
+			req:        &pb.CreateWebhookEndpointRequest{UserId: userId, Url: "ftp://example.com/hooks"},
+			wantFields: []string{"url"},
+		},
+		{
+			name:       "webhook deliveries of an account",
+			req:        &pb.ListWebhookDeliveriesRequest{EndpointId: acct1, UserId: userId, DeliveryState: "failed", Cursor: -1},
+			wantFields: []string{"endpoint_id", "delivery_state", "cursor"},
+		},
+		{
+			name:       "redeliver without a delivery",
+			req:        &pb.RedeliverWebhookRequest{},
+			wantFields: []string{"delivery_id", "user_id"},
+		},
+		{
+			name:       "delete a webhook endpoint without its user",
+			req:        &pb.DeleteWebhookEndpointRequest{EndpointId: endpointId},
+			wantFields: []string{"user_id"},
+		},
+		{
+			name: "deposit with a payment method",
+			req:  &pb.DepositFundsRequest{Amount: usd(100), UserId: userId, DebitAccountId: acct1, CreditAccountId: acct2, PaymentMethodId: paymentMethodId},
+		},
+		{
+			name:       "withdrawal with a malformed payment method",
+			req:        &pb.WithdrawFundsRequest{Amount: usd(100), UserId: userId, DebitAccountId: acct1, CreditAccountId: acct2, PaymentMethodId: acct1},
+			wantFields: []string{"payment_method_id"},
+		},
+		{
+			name: "valid ACH payment method",
+			req:  &pb.AddPaymentMethodRequest{UserId: userId, MethodType: "ACH", AccountNumber: "000123456789", RoutingNumber: "011000015"},
+		},
+		{
+			name:       "ACH payment method with a bad routing number and card details",
+			req:        &pb.AddPaymentMethodRequest{UserId: userId, MethodType: "ACH", AccountNumber: "12-34", RoutingNumber: "011000016", CardNumber: "4111111111111111"},
+			wantFields: []string{"account_number", "routing_number", "card_number"},
+		},
+		{
+			name: "valid card",
+			req:  &pb.AddPaymentMethodRequest{UserId: userId, MethodType: "card", CardNumber: "4111111111111111", ExpirationMonth: 1, ExpirationYear: nextYear},
+		},
+		{
+			name:       "card that fails the Luhn check",
+			req:        &pb.AddPaymentMethodRequest{UserId: userId, MethodType: "card", CardNumber: "4111111111111112", ExpirationMonth: 12, ExpirationYear: nextYear},
+			wantFields: []string{"card_number"},
+		},
+		{
+			name:       "expired card",
+			req:        &pb.AddPaymentMethodRequest{UserId: userId, MethodType: "card", CardNumber: "4111111111111111", ExpirationMonth: 12, ExpirationYear: nextYear - 2},
+			wantFields: []string{"expiration_year"},
+		},
+		{
+			name:       "card without an expiration month",
+			req:        &pb.AddPaymentMethodRequest{UserId: userId, MethodType: "card", CardNumber: "4111111111111111", ExpirationYear: nextYear},
+			wantFields: []string{"expiration_month"},
+		},
+		{
+			name:       "unknown payment method type",
+			req:        &pb.AddPaymentMethodRequest{UserId: userId, MethodType: "paypal"},
+			wantFields: []string{"method_type"},
+		},
+		{
+			name:       "verify a payment method without its user",
+			req:        &pb.PaymentMethodRequest{PaymentMethodId: paymentMethodId},
+			wantFields: []string{"user_id"},
+		},
+		{
+			name:       "watch balance without an account",
+			req:        &pb.WatchBalanceRequest{},
+			wantFields: []string{"account_id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			for _, fv := range validateRequest(tt.req) {
+				fields = append(fields, fv.Field)
+			}
+			assert.Equal(t, tt.wantFields, fields)
+		})
+	}
+}
+
+func TestValidationUnaryServerInterceptor(t *testing.T) {
+	interceptor := ValidationUnaryServerInterceptor()
+	called := false
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	}
+
+	_, err := interceptor(context.Background(), &pb.VoidHoldRequest{}, &grpclib.UnaryServerInfo{}, handler)
+	assert.False(t, called)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	var fields []string
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, fv := range br.FieldViolations {
+				fields = append(fields, fv.Field)
+			}
+		}
+	}
+	assert.Equal(t, []string{"hold_id", "user_id"}, fields)
+}
+
+// recvServerStream is a server stream whose client sends a single message
+type recvServerStream struct {
+	grpclib.ServerStream
+	req proto.Message
+}
+
+func (s *recvServerStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.req)
+	return nil
+}
+
+func TestValidationStreamServerInterceptor(t *testing.T) {
+	interceptor := ValidationStreamServerInterceptor()
+	handler := func(srv interface{}, ss grpclib.ServerStream) error {
+		return ss.RecvMsg(&pb.WatchTransactionsRequest{})
+	}
+
+	err := interceptor(nil, &recvServerStream{req: &pb.WatchTransactionsRequest{AccountId: "acct_1", FromCursor: -1}}, &grpclib.StreamServerInfo{}, handler)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	err = interceptor(nil, &recvServerStream{req: &pb.WatchTransactionsRequest{AccountId: string(identifier.ID(accountIDPrefix).New())}}, &grpclib.StreamServerInfo{}, handler)
+	assert.NoError(t, err)
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/broadcast"
 	"github.com/rasha-hantash/chariot-takehome/api/pkgs/logger"
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/pagetoken"
 	"github.com/rasha-hantash/chariot-takehome/api/pkgs/postgres"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
@@ -18,6 +24,8 @@ import (
 	service "github.com/rasha-hantash/chariot-takehome/api/grpc"
 	pb "github.com/rasha-hantash/chariot-takehome/api/grpc/proto"
 	"github.com/rasha-hantash/chariot-takehome/api/grpc/repository"
+	"github.com/rasha-hantash/chariot-takehome/api/relay"
+	"github.com/rasha-hantash/chariot-takehome/api/webhooks"
 )
 
 type DatabaseConfig struct {
@@ -32,8 +40,36 @@ type DatabaseConfig struct {
 type Config struct {
 	ServerPort         string `env:"PORT" envDefault:"9093"`
 	Database           DatabaseConfig
-	Mode               string `env:"MODE" envDefault:"local"`
-	AuthorizedAgentUrl string `env:"AUTHORIZED_AGENT_URL" envDefault:""`
+	Mode               string        `env:"MODE" envDefault:"local"`
+	AuthorizedAgentUrl string        `env:"AUTHORIZED_AGENT_URL" envDefault:""`
+	HoldExpiryInterval time.Duration `env:"HOLD_EXPIRY_INTERVAL" envDefault:"1m"`
+	// IdempotencyKeyTTL is how long a stored response is replayed for retries with the same key
+	IdempotencyKeyTTL time.Duration `env:"IDEMPOTENCY_KEY_TTL" envDefault:"24h"`
+	// IdempotencyKeyInProgressTTL is how long a key is held by a request that has not committed
+	// anything yet; it must be longer than any request takes
+	IdempotencyKeyInProgressTTL time.Duration `env:"IDEMPOTENCY_KEY_IN_PROGRESS_TTL" envDefault:"1m"`
+	IdempotencyPurgeInterval    time.Duration `env:"IDEMPOTENCY_PURGE_INTERVAL" envDefault:"1h"`
+	// PageTokenSecret signs page tokens; every replica must share it for tokens to work across them
+	PageTokenSecret string `env:"PAGE_TOKEN_SECRET" envDefault:""`
+	// RelayFilePath is the file ledger events are appended to, "-" for stdout; the file sink
+	// is disabled when it is empty, as is the webhook sink without RelayWebhookURL
+	RelayFilePath       string        `env:"RELAY_FILE_PATH" envDefault:""`
+	RelayWebhookURL     string        `env:"RELAY_WEBHOOK_URL" envDefault:""`
+	RelayWebhookTimeout time.Duration `env:"RELAY_WEBHOOK_TIMEOUT" envDefault:"10s"`
+	RelayPollInterval   time.Duration `env:"RELAY_POLL_INTERVAL" envDefault:"1s"`
+	RelayBatchSize      int           `env:"RELAY_BATCH_SIZE" envDefault:"100"`
+	// WatchPollInterval is how often watch RPCs look for new ledger events when no
+	// notification arrives, e.g. while the listener is reconnecting
+	WatchPollInterval time.Duration `env:"WATCH_POLL_INTERVAL" envDefault:"10s"`
+	// WebhookTimeout bounds each attempt to deliver a webhook
+	WebhookTimeout        time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+	WebhookPollInterval   time.Duration `env:"WEBHOOK_POLL_INTERVAL" envDefault:"1s"`
+	WebhookBatchSize      int           `env:"WEBHOOK_BATCH_SIZE" envDefault:"20"`
+	// A failed delivery is attempted again after 30s, doubling up to 6h between attempts, and
+	// is dead after 10 attempts, about 4 hours after the first
+	WebhookMaxAttempts    int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"10"`
+	WebhookRetryBaseDelay time.Duration `env:"WEBHOOK_RETRY_BASE_DELAY" envDefault:"30s"`
+	WebhookRetryMaxDelay  time.Duration `env:"WEBHOOK_RETRY_MAX_DELAY" envDefault:"6h"`
 }
 
 func main() {
@@ -66,8 +102,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	idempotencyKeys := repository.NewIdempotencyRepository(db)
+
 	grpcOpts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(logger.ContextPropagationUnaryServerInterceptor()),
+		grpc.ChainUnaryInterceptor(
+			logger.ContextPropagationUnaryServerInterceptor(),
+			service.ErrorUnaryServerInterceptor(),
+			service.ValidationUnaryServerInterceptor(),
+			service.IdempotencyUnaryServerInterceptor(idempotencyKeys, c.IdempotencyKeyTTL, c.IdempotencyKeyInProgressTTL),
+		),
+		grpc.ChainStreamInterceptor(
+			logger.ContextPropagationStreamServerInterceptor(),
+			service.ErrorStreamServerInterceptor(),
+			service.ValidationStreamServerInterceptor(),
+		),
 	}
 	
 	// Create a gRPC server with an interceptor that uses the logger
@@ -77,9 +125,58 @@ func main() {
 	t := repository.NewTransactionRepository(db, "txn_", "le_")
 	a := repository.NewAccountRepository(db, "acct_")
 	u := repository.NewUserRepository(db, a, "usr_")
+	hr := repository.NewHoldRepository(db, t, "hold_")
+	wh := repository.NewWebhookRepository(db, "whk_")
+	pm := repository.NewPaymentMethodRepository(db, "pm_")
+
+	pageTokenSecret := []byte(c.PageTokenSecret)
+	if len(pageTokenSecret) == 0 {
+		// Without a configured secret, page tokens only work against this process until it restarts
+		slog.Warn("PAGE_TOKEN_SECRET is not set, using a random secret")
+		pageTokenSecret = make([]byte, 32)
+		if _, err := rand.Read(pageTokenSecret); err != nil {
+			slog.Error("failed to generate page token secret", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Wake up watch RPCs whenever ledger events are committed
+	events := repository.NewLedgerEventRepository(db)
+	eventWakeups := broadcast.New()
+	go postgres.Listen(context.Background(), c.Database.ConnString, "ledger_events", c.WatchPollInterval, eventWakeups.Broadcast)
 
 	// Register your service
-	pb.RegisterApiServiceServer(s, &service.GrpcService{UserRepo: u, AccountRepo: a, TransactionRepo: t})
+	pb.RegisterApiServiceServer(s, &service.GrpcService{UserRepo: u, AccountRepo: a, TransactionRepo: t, HoldRepo: hr, PageTokens: pagetoken.NewCodec(pageTokenSecret),
+		Events: events, EventWakeups: eventWakeups, WebhookRepo: wh, PaymentMethodRepo: pm})
+
+	// Release holds that were neither captured nor voided before they expired
+	go expireHolds(context.Background(), hr, c.HoldExpiryInterval)
+
+	// Forget idempotency keys once their responses can no longer be replayed
+	go purgeIdempotencyKeys(context.Background(), idempotencyKeys, c.IdempotencyPurgeInterval)
+
+	// Publish ledger events from the outbox to webhook endpoints and the configured sinks
+	sinks := []relay.Sink{webhooks.NewFanoutSink(wh)}
+	if c.RelayFilePath != "" {
+		fileSink, err := relay.NewFileSink(c.RelayFilePath)
+		if err != nil {
+			slog.Error("failed to open ledger event file", "error", err)
+			os.Exit(1)
+		}
+		sinks = append(sinks, fileSink)
+	}
+	if c.RelayWebhookURL != "" {
+		sinks = append(sinks, relay.NewWebhookSink(c.RelayWebhookURL, &http.Client{Timeout: c.RelayWebhookTimeout}))
+	}
+	go relay.New(events, c.RelayPollInterval, c.RelayBatchSize, sinks...).Run(context.Background())
+
+	// Deliver webhooks, treating redirects as failures rather than following them
+	webhookClient := &http.Client{
+		Timeout:       c.WebhookTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	webhookRetry := repository.RetryPolicy{MaxAttempts: c.WebhookMaxAttempts, BaseDelay: c.WebhookRetryBaseDelay, MaxDelay: c.WebhookRetryMaxDelay}
+	go webhooks.NewWorker(wh, webhookClient, webhookRetry, c.WebhookPollInterval, c.WebhookBatchSize).Run(context.Background())
 
 	// Create and register the health server
 	healthServer := health.NewServer()
@@ -94,4 +191,48 @@ func main() {
 		slog.Error("failed to serve", "error", err)
 		os.Exit(1)
 	}
-}
\ No newline at end of file
+}
+
+// expireHolds periodically releases expired holds back to their accounts' available balance
+func expireHolds(ctx context.Context, holds *repository.HoldRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := holds.ExpireHolds(ctx)
+			if err != nil {
+				slog.Error("failed to expire holds", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.Info("expired holds", "count", n)
+			}
+		}
+	}
+}
+
+// purgeIdempotencyKeys periodically deletes expired idempotency keys
+func purgeIdempotencyKeys(ctx context.Context, keys *repository.IdempotencyRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := keys.PurgeExpired(ctx)
+			if err != nil {
+				slog.Error("failed to purge idempotency keys", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.Info("purged idempotency keys", "count", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Package broadcast wakes up every subscriber when something has happened, without saying
+// what. A subscriber that is busy when broadcasts are sent sees one wake-up however many it
+// missed, so it must look up what changed itself; in exchange a slow subscriber never holds
+// up the broadcaster or the other subscribers.
+package broadcast
+
+import "sync"
+
+type Broadcaster struct {
+	mu   sync.Mutex
+	subs map[chan struct{}]struct{}
+}
+
+func New() *Broadcaster {
+	return &Broadcaster{subs: make(map[chan struct{}]struct{})}
+}
+
+// Subscribe returns a channel that receives after every broadcast from now on, and a
+// function that unsubscribes it
+func (b *Broadcaster) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+// Broadcast wakes up every subscriber
+func (b *Broadcaster) Broadcast() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// The subscriber has a wake-up pending already
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package broadcast
+
+import "testing"
+
+func pending(ch <-chan struct{}) int {
+	n := 0
+	for {
+		select {
+		case <-ch:
+			n++
+		default:
+			return n
+		}
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	b := New()
+	first, unsubscribeFirst := b.Subscribe()
+	second, unsubscribeSecond := b.Subscribe()
+	defer unsubscribeSecond()
+
+	// Broadcasts a subscriber has not caught up with yet are folded into one wake-up
+	b.Broadcast()
+	b.Broadcast()
+	if n := pending(first); n != 1 {
+		t.Errorf("first subscriber woke up %d times, want 1", n)
+	}
+	if n := pending(second); n != 1 {
+		t.Errorf("second subscriber woke up %d times, want 1", n)
+	}
+
+	unsubscribeFirst()
+	b.Broadcast()
+	if n := pending(first); n != 0 {
+		t.Errorf("unsubscribed subscriber woke up %d times, want 0", n)
+	}
+	if n := pending(second); n != 1 {
+		t.Errorf("second subscriber woke up %d times, want 1", n)
+	}
+}
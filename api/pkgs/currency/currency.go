@@ -0,0 +1,62 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Default is the currency used when an account is created without one
+const Default = "USD"
+
+// ErrUnsupported is returned when looking up a currency code that is not supported
+var ErrUnsupported = errors.New("unsupported currency")
+
+// Currency is an ISO 4217 currency together with its minor-unit exponent,
+// i.e. the number of decimal places between the major and minor unit
+// (2 for USD cents, 0 for JPY, 3 for KWD fils).
+type Currency struct {
+	Code     string
+	Exponent int
+}
+
+var currencies = map[string]Currency{
+	"AUD": {Code: "AUD", Exponent: 2},
+	"BHD": {Code: "BHD", Exponent: 3},
+	"BRL": {Code: "BRL", Exponent: 2},
+	"CAD": {Code: "CAD", Exponent: 2},
+	"CHF": {Code: "CHF", Exponent: 2},
+	"CLP": {Code: "CLP", Exponent: 0},
+	"CNY": {Code: "CNY", Exponent: 2},
+	"DKK": {Code: "DKK", Exponent: 2},
+	"EUR": {Code: "EUR", Exponent: 2},
+	"GBP": {Code: "GBP", Exponent: 2},
+	"HKD": {Code: "HKD", Exponent: 2},
+	"INR": {Code: "INR", Exponent: 2},
+	"ISK": {Code: "ISK", Exponent: 0},
+	"JOD": {Code: "JOD", Exponent: 3},
+	"JPY": {Code: "JPY", Exponent: 0},
+	"KRW": {Code: "KRW", Exponent: 0},
+	"KWD": {Code: "KWD", Exponent: 3},
+	"MXN": {Code: "MXN", Exponent: 2},
+	"NGN": {Code: "NGN", Exponent: 2},
+	"NOK": {Code: "NOK", Exponent: 2},
+	"NZD": {Code: "NZD", Exponent: 2},
+	"OMR": {Code: "OMR", Exponent: 3},
+	"PLN": {Code: "PLN", Exponent: 2},
+	"SEK": {Code: "SEK", Exponent: 2},
+	"SGD": {Code: "SGD", Exponent: 2},
+	"TND": {Code: "TND", Exponent: 3},
+	"USD": {Code: "USD", Exponent: 2},
+	"VND": {Code: "VND", Exponent: 0},
+	"ZAR": {Code: "ZAR", Exponent: 2},
+}
+
+// Lookup returns the currency for an ISO 4217 code. Codes are matched case-insensitively.
+func Lookup(code string) (Currency, error) {
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w %q", ErrUnsupported, code)
+	}
+	return c, nil
+}
@@ -0,0 +1,34 @@
+package currency
+
+import (
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name         string
+		code         string
+		wantExponent int
+		wantErr      bool
+	}{
+		{"US dollar", "USD", 2, false},
+		{"Lowercase code", "eur", 2, false},
+		{"Zero decimal currency", "JPY", 0, false},
+		{"Three decimal currency", "KWD", 3, false},
+		{"Unknown code", "XYZ", 0, true},
+		{"Empty code", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Lookup(tt.code)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got.Exponent != tt.wantExponent {
+				t.Errorf("Lookup() exponent = %d, want %d", got.Exponent, tt.wantExponent)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package identifier
 
 import (
+	"crypto/rand"
 	"errors"
+	"math/big"
 	"strings"
 	"time"
 	"unicode"
@@ -17,23 +19,26 @@ type Identifier[T any] interface {
 type ID string
 
 const (
-	alphabet        = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	digits          = "0123456789"
+	alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits   = "0123456789"
+	// idLength is the length of legacy IDs, whose random part filled whatever the prefix and
+	// timestamp left of it. They are still valid.
 	idLength        = 20
 	timestampLength = 12
+	// randomLength is the length of the random part of new IDs, about 48 bits of entropy, so
+	// IDs made in the same second with the same prefix practically never collide
+	randomLength = 12
 )
 
 func (i ID) New() ID {
 	timestamp := time.Now().Format("060102150405") // YYMMDDhhmmss
-	nanoseconds := time.Now().Nanosecond()
 
-	randomPart := make([]byte, idLength-len(string(i))-len(timestamp))
+	randomPart := make([]byte, randomLength)
 	for idx := range randomPart {
-		// Use the nanoseconds value to add some entropy
 		if idx%2 == 0 {
-			randomPart[idx] = alphabet[(nanoseconds+idx)%len(alphabet)]
+			randomPart[idx] = randomChar(alphabet)
 		} else {
-			randomPart[idx] = digits[(nanoseconds+idx)%len(digits)]
+			randomPart[idx] = randomChar(digits)
 		}
 	}
 
@@ -41,6 +46,16 @@ func (i ID) New() ID {
 	return ID(id)
 }
 
+// randomChar picks a character of set uniformly at random
+func randomChar(set string) byte {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+	if err != nil {
+		// The system's random source is broken and no ID can be trusted to be unique
+		panic("identifier: reading random bytes: " + err.Error())
+	}
+	return set[n.Int64()]
+}
+
 func (i ID) FromString(s string) (ID, error) {
 	id := ID(s)
 	if !id.Validate() {
@@ -60,11 +75,6 @@ func (i ID) FromBytes(b []byte) (ID, error) {
 func (i ID) Validate() bool {
 	idStr := string(i)
 
-	// Ensure the length is correct
-	if len(idStr) != idLength {
-		return false
-	}
-
 	// Extract the prefix, timestamp, and random part
 	// Find the first underscore to determine the prefix
 	underscoreIndex := strings.Index(idStr, "_")
@@ -82,6 +92,11 @@ func (i ID) Validate() bool {
 	timestamp := remaining[:timestampLength]
 	randomPart := remaining[timestampLength:]
 
+	// Ensure the length is correct, either that of a legacy ID or of a new random part
+	if len(idStr) != idLength && len(randomPart) != randomLength {
+		return false
+	}
+
 	// Validate the timestamp part
 	if _, err := time.Parse("060102150405", timestamp); err != nil {
 		return false
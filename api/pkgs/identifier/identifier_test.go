@@ -1,6 +1,7 @@
 package identifier
 
 import (
+	"sync"
 	"testing"
 	"time"
 )
@@ -25,8 +26,11 @@ func TestNew(t *testing.T) {
 			}
 
 			// Check that the total length of the ID is correct
-			if len(id) != idLength {
-				t.Errorf("New() = %v, length = %d, want length = %d", id, len(id), idLength)
+			if want := len(tt.prefix) + timestampLength + randomLength; len(id) != want {
+				t.Errorf("New() = %v, length = %d, want length = %d", id, len(id), want)
+			}
+			if !id.Validate() {
+				t.Errorf("New() = %v, does not validate", id)
 			}
 
 			// Extract the timestamp and random part from the ID
@@ -55,6 +59,32 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewIsUnique(t *testing.T) {
+	// IDs made concurrently within the same second must not collide
+	const workers, perWorker = 16, 1000
+	ids := make(chan ID, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				ids <- ID("le_").New()
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[ID]bool, workers*perWorker)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("New() returned %v twice", id)
+		}
+		seen[id] = true
+	}
+}
+
 func hasPrefix(s, prefix string) bool {
 	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
 }
@@ -113,7 +143,9 @@ func TestValidate(t *testing.T) {
 		want bool
 	}{
 		{"Valid ID", "acct_240714212559C7E", true},
+		{"Valid ID with a full random part", "acct_240714212559A1B2C3D4E5F6", true},
 		{"Invalid length", "acct_240714212559A1", false},
+		{"Random part too long", "acct_240714212559A1B2C3D4E5F6G", false},
 		{"Invalid characters", "acct_240714212559A1B@", false},
 		{"Invalid timestamp", "acct_991231123059A1B2", false},
 		{"Missing underscore", "acct240714212559A1B2", false},
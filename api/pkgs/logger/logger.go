@@ -39,6 +39,38 @@ func ContextPropagationUnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
+// ContextPropagationStreamServerInterceptor is ContextPropagationUnaryServerInterceptor for
+// streaming RPCs
+func ContextPropagationStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return fmt.Errorf("couldn't parse incoming context metadata")
+		}
+
+		for k, v := range md {
+			if len(v) > 1 {
+				ctx = AppendCtx(ctx, slog.Any(k, v))
+			} else {
+				ctx = AppendCtx(ctx, slog.String(k, v[0]))
+			}
+		}
+		slog.InfoContext(ctx, "gRPC stream", "method", info.FullMethod)
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextServerStream is a server stream with its context replaced
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
 // Handle adds contextual attributes to the Record before calling the underlying handler
 func (h ContextHandler) Handle(ctx context.Context, r slog.Record) error {
 	if attrs, ok := ctx.Value(slogFields).([]slog.Attr); ok {
@@ -0,0 +1,108 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/currency"
+)
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrNegativeAmount   = errors.New("amount must not be negative")
+	ErrSubMinorUnit     = errors.New("amount is more precise than the currency's minor unit")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// Money is an exact amount expressed in minor units (e.g. cents) of an ISO 4217 currency.
+// Amounts are never represented as floating point anywhere between the API and the database.
+type Money struct {
+	Units    int64
+	Currency string
+}
+
+// New returns a non-negative amount of minor units in the given currency
+func New(units int64, code string) (Money, error) {
+	cur, err := currency.Lookup(code)
+	if err != nil {
+		return Money{}, err
+	}
+	if units < 0 {
+		return Money{}, ErrNegativeAmount
+	}
+	return Money{Units: units, Currency: cur.Code}, nil
+}
+
+// Parse converts a decimal string in major units (e.g. "10.55") into minor units of the
+// given currency. Negative amounts and amounts with more decimal places than the
+// currency's exponent (e.g. "10.555" USD) are rejected rather than rounded.
+func Parse(amount, code string) (Money, error) {
+	cur, err := currency.Lookup(code)
+	if err != nil {
+		return Money{}, err
+	}
+
+	amount = strings.TrimSpace(amount)
+	if strings.HasPrefix(amount, "-") {
+		return Money{}, ErrNegativeAmount
+	}
+	whole, frac, _ := strings.Cut(amount, ".")
+	if whole == "" && frac == "" {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	// Trailing zeros beyond the exponent do not add precision ("10.500" USD is fine)
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > cur.Exponent {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimal places for %s", ErrSubMinorUnit, amount, cur.Exponent, cur.Code)
+	}
+	frac += strings.Repeat("0", cur.Exponent-len(frac))
+
+	digits := strings.TrimLeft(whole+frac, "0")
+	if digits == "" {
+		return Money{Currency: cur.Code}, nil
+	}
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, amount)
+	}
+	return Money{Units: units, Currency: cur.Code}, nil
+}
+
+// Format renders the amount in major units with exactly the currency's number of decimal places
+func (m Money) Format() string {
+	cur, err := currency.Lookup(m.Currency)
+	if err != nil || cur.Exponent == 0 {
+		return strconv.FormatInt(m.Units, 10)
+	}
+
+	sign := ""
+	// math.MinInt64 cannot be negated, format its magnitude via uint64
+	abs := uint64(m.Units)
+	if m.Units < 0 {
+		sign = "-"
+		abs = uint64(-(m.Units + 1)) + 1
+	}
+	scale := uint64(math.Pow10(cur.Exponent))
+	return fmt.Sprintf("%s%d.%0*d", sign, abs/scale, cur.Exponent, abs%scale)
+}
+
+// String renders the amount with its currency code, e.g. "10.55 USD"
+func (m Money) String() string {
+	return m.Format() + " " + m.Currency
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
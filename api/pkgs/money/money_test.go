@@ -0,0 +1,113 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		units   int64
+		code    string
+		want    Money
+		wantErr error
+	}{
+		{"Valid amount", 1055, "USD", Money{Units: 1055, Currency: "USD"}, nil},
+		{"Lowercase currency is normalised", 1, "eur", Money{Units: 1, Currency: "EUR"}, nil},
+		{"Zero amount", 0, "USD", Money{Units: 0, Currency: "USD"}, nil},
+		{"Negative amount", -1, "USD", Money{}, ErrNegativeAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.units, tt.code)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := New(100, "XYZ"); err == nil {
+		t.Errorf("New() with unknown currency expected an error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  string
+		code    string
+		want    int64
+		wantErr error
+	}{
+		{"Dollars and cents", "10.55", "USD", 1055, nil},
+		{"Whole dollars", "10", "USD", 1000, nil},
+		{"Single decimal", "10.5", "USD", 1050, nil},
+		{"Trailing zeros", "10.500", "USD", 1050, nil},
+		{"Leading decimal point", ".25", "USD", 25, nil},
+		{"Zero decimal currency", "1500", "JPY", 1500, nil},
+		{"Three decimal currency", "1.234", "KWD", 1234, nil},
+		{"Zero", "0.00", "USD", 0, nil},
+		{"Sub-cent amount", "10.555", "USD", 0, ErrSubMinorUnit},
+		{"Fractional yen", "1.5", "JPY", 0, ErrSubMinorUnit},
+		{"Negative amount", "-10.00", "USD", 0, ErrNegativeAmount},
+		{"Not a number", "ten", "USD", 0, ErrInvalidAmount},
+		{"Empty string", "", "USD", 0, ErrInvalidAmount},
+		{"Exponent notation", "1e3", "USD", 0, ErrInvalidAmount},
+		{"Overflow", "999999999999999999999", "USD", 0, ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.amount, tt.code)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got.Units != tt.want {
+				t.Errorf("Parse() = %d, want %d", got.Units, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		money Money
+		want  string
+	}{
+		{"Dollars and cents", Money{Units: 1055, Currency: "USD"}, "10.55"},
+		{"Cents only", Money{Units: 5, Currency: "USD"}, "0.05"},
+		{"Negative balance", Money{Units: -1055, Currency: "USD"}, "-10.55"},
+		{"Zero decimal currency", Money{Units: 1500, Currency: "JPY"}, "1500"},
+		{"Three decimal currency", Money{Units: 1234, Currency: "KWD"}, "1.234"},
+		{"Smallest int64", Money{Units: math.MinInt64, Currency: "USD"}, "-92233720368547758.08"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.money.Format(); got != tt.want {
+				t.Errorf("Format() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFormatRoundTrip(t *testing.T) {
+	for _, s := range []string{"0.01", "10.55", "123456.78"} {
+		m, err := Parse(s, "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := m.Format(); got != s {
+			t.Errorf("Format(Parse(%q)) = %q", s, got)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package pagetoken
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	c := NewCodec([]byte("secret"))
+	want := Token{
+		Filters:   Fingerprint([]byte("account_id=acct_1")),
+		CreatedAt: time.Date(2024, 1, 31, 23, 59, 59, 123456000, time.UTC),
+		ID:        "txn_1",
+		Backward:  true,
+	}
+
+	s, err := c.Encode(want)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := c.Decode(s, want.Filters)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID || got.Backward != want.Backward || got.Filters != want.Filters {
+		t.Errorf("Decode() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeRejects(t *testing.T) {
+	c := NewCodec([]byte("secret"))
+	filters := Fingerprint([]byte("account_id=acct_1"))
+	valid, err := c.Encode(Token{Filters: filters, CreatedAt: time.Now(), ID: "txn_1"})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	otherKey, err := NewCodec([]byte("other")).Encode(Token{Filters: filters, CreatedAt: time.Now(), ID: "txn_1"})
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(valid)
+	raw[len(raw)/2] ^= 1
+	tampered := base64.RawURLEncoding.EncodeToString(raw)
+
+	tests := []struct {
+		name    string
+		token   string
+		filters string
+	}{
+		{"Raw transaction ID", "txn_1", filters},
+		{"Not base64", "%%%", filters},
+		{"Tampered payload", tampered, filters},
+		{"Signed with another key", otherKey, filters},
+		{"Different filters", valid, Fingerprint([]byte("account_id=acct_2"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Decode(tt.token, tt.filters); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Decode() error = %v, want %v", err, ErrInvalid)
+			}
+		})
+	}
+}
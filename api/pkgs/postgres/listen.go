@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Listen calls notify for every notification sent on a Postgres channel until the context is
+// cancelled. Notifications sent while the connection is down are lost, so notify is also
+// called whenever the connection is re-established and at least once every pollInterval;
+// if the channel cannot be listened on at all, that polling is all that is left.
+func Listen(ctx context.Context, connString, channel string, pollInterval time.Duration, notify func()) {
+	listener := pq.NewListener(connString, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("postgres listener connection error", "channel", channel, "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		slog.Error("failed to listen for notifications, polling instead", "channel", channel, "error", err)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		// A nil notification means the connection was re-established
+		case <-listener.Notify:
+			notify()
+		case <-ticker.C:
+			notify()
+		}
+	}
+}
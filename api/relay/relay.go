@@ -0,0 +1,110 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/grpc/repository"
+)
+
+// maxRetryDelay caps the backoff after a sink fails
+const maxRetryDelay = time.Minute
+
+// Event is a ledger event as it is published to sinks
+type Event struct {
+	Id int64 `json:"id"`
+	// Position orders events by when the change they record was committed. A sink sees
+	// positions in increasing order, but may see an event again after a failure.
+	Position    int64           `json:"position"`
+	Type        string          `json:"type"`
+	AggregateId string          `json:"aggregate_id"`
+	Payload     json.RawMessage `json:"payload"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Sink is a destination for ledger events. Publish must deliver every event of the batch or
+// return an error, in which case the batch is published again later.
+type Sink interface {
+	// Name identifies the sink's checkpoint, so it must stay the same across restarts
+	Name() string
+	Publish(ctx context.Context, events []Event) error
+}
+
+// Relay publishes the events in the ledger_events outbox to sinks, in commit order and at
+// least once. Each sink is relayed to independently from its own checkpoint, so a sink that
+// is down does not hold back the others.
+type Relay struct {
+	events       *repository.LedgerEventRepository
+	sinks        []Sink
+	pollInterval time.Duration
+	batchSize    int
+}
+
+func New(events *repository.LedgerEventRepository, pollInterval time.Duration, batchSize int, sinks ...Sink) *Relay {
+	return &Relay{events: events, sinks: sinks, pollInterval: pollInterval, batchSize: batchSize}
+}
+
+// Run relays events to every sink until the context is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, sink := range r.sinks {
+		wg.Add(1)
+		go func(sink Sink) {
+			defer wg.Done()
+			r.relay(ctx, sink)
+		}(sink)
+	}
+	wg.Wait()
+}
+
+func (r *Relay) relay(ctx context.Context, sink Sink) {
+	retryDelay := r.pollInterval
+	for {
+		n, err := r.events.RelayBatch(ctx, sink.Name(), r.batchSize, func(events []repository.LedgerEvent) error {
+			return sink.Publish(ctx, toEvents(events))
+		})
+
+		var delay time.Duration
+		switch {
+		case err != nil:
+			slog.ErrorContext(ctx, "failed to relay ledger events", "sink", sink.Name(), "retry_in", retryDelay, "error", err)
+			delay = retryDelay
+			retryDelay = min(retryDelay*2, maxRetryDelay)
+		case n == r.batchSize:
+			// A full batch means more events may be waiting
+			retryDelay = r.pollInterval
+		default:
+			if n > 0 {
+				slog.DebugContext(ctx, "relayed ledger events", "sink", sink.Name(), "count", n)
+			}
+			delay = r.pollInterval
+			retryDelay = r.pollInterval
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func toEvents(events []repository.LedgerEvent) []Event {
+	out := make([]Event, len(events))
+	for i, e := range events {
+		out[i] = Event{
+			Id:          e.Id,
+			Position:    e.Position,
+			Type:        e.Type,
+			AggregateId: e.AggregateId,
+			Payload:     e.Payload,
+			CreatedAt:   e.CreatedAt,
+		}
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// FileSink appends events to a file, or writes them to stdout, one JSON object per line
+type FileSink struct {
+	w io.Writer
+	// file is synced after every batch so events are on disk before the checkpoint moves
+	file *os.File
+}
+
+// NewFileSink opens a file sink. A path of "-" writes to stdout.
+func NewFileSink(path string) (*FileSink, error) {
+	if path == "-" {
+		return &FileSink{w: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening event file %s: %w", path, err)
+	}
+	return &FileSink{w: f, file: f}, nil
+}
+
+func (f *FileSink) Name() string {
+	return "file"
+}
+
+func (f *FileSink) Publish(ctx context.Context, events []Event) error {
+	enc := json.NewEncoder(f.w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("error writing event %d: %w", e.Id, err)
+		}
+	}
+	if f.file != nil {
+		if err := f.file.Sync(); err != nil {
+			return fmt.Errorf("error syncing event file: %w", err)
+		}
+	}
+	return nil
+}
+
+// WebhookSink POSTs each batch of events to a URL as {"events": [...]}. Any response other
+// than a 2xx is a failure and the batch is sent again.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	return &WebhookSink{url: url, client: client}
+}
+
+func (w *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (w *WebhookSink) Publish(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(struct {
+		Events []Event `json:"events"`
+	}{events})
+	if err != nil {
+		return fmt.Errorf("error encoding events: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
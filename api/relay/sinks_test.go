@@ -0,0 +1,83 @@
+package relay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvents() []Event {
+	return []Event{
+		{Id: 1, Position: 1, Type: "transaction.created", AggregateId: "txn_1", Payload: json.RawMessage(`{"transaction_id":"txn_1"}`), CreatedAt: time.Now().UTC()},
+		{Id: 2, Position: 2, Type: "balance.changed", AggregateId: "acct_1", Payload: json.RawMessage(`{"account_id":"acct_1"}`), CreatedAt: time.Now().UTC()},
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewFileSink(path)
+	assert.NoError(t, err)
+
+	// Batches are appended, so a redelivered batch shows up twice rather than being lost
+	assert.NoError(t, sink.Publish(context.Background(), testEvents()))
+	assert.NoError(t, sink.Publish(context.Background(), testEvents()[1:]))
+
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	var ids []int64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Event
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		ids = append(ids, e.Id)
+	}
+	assert.Equal(t, []int64{1, 2, 2}, ids)
+}
+
+func TestWebhookSink(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"accepted", http.StatusOK, false},
+		{"no content", http.StatusNoContent, false},
+		{"server error", http.StatusInternalServerError, true},
+		{"redirect", http.StatusFound, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received struct {
+				Events []Event `json:"events"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+			err := NewWebhookSink(server.URL, client).Publish(context.Background(), testEvents())
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, received.Events, 2)
+			assert.Equal(t, "txn_1", received.Events[0].AggregateId)
+		})
+	}
+}
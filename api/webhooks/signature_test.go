@@ -0,0 +1,44 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"id":1}`)
+	signedAt := time.Unix(1700000000, 0)
+	header := Sign(secret, signedAt, body)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    []byte
+		now     time.Time
+		wantErr bool
+	}{
+		{"valid", secret, header, body, signedAt.Add(time.Minute), false},
+		{"one of several signatures matches", secret, header + ",v1=00", body, signedAt, false},
+		{"other secret", "whsec_other", header, body, signedAt, true},
+		{"tampered body", secret, header, []byte(`{"id":2}`), signedAt, true},
+		{"too old", secret, header, body, signedAt.Add(6 * time.Minute), true},
+		{"from the future", secret, header, body, signedAt.Add(-6 * time.Minute), true},
+		{"no timestamp", secret, "v1=" + header[len("t=1700000000,v1="):], body, signedAt, true},
+		{"no signature", secret, "t=1700000000", body, signedAt, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.body, 5*time.Minute, tt.now)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidSignature)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
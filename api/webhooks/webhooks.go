@@ -0,0 +1,183 @@
+// Package webhooks sends ledger events to the webhook endpoints users have registered.
+//
+// The relay hands every ledger event to FanoutSink, which schedules a delivery of it to each
+// endpoint that is sent it. Worker then attempts the due deliveries: each is POSTed to its
+// endpoint signed with the endpoint's secret, and retried with exponential backoff until the
+// endpoint accepts it or it runs out of attempts and is dead.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/grpc/repository"
+	"github.com/rasha-hantash/chariot-takehome/api/relay"
+)
+
+// maxErrorBodyLen bounds how much of a failed response's body is kept in the delivery log
+const maxErrorBodyLen = 512
+
+// Backoff returns how long to wait after a delivery's attempt-th failed attempt before the
+// next one, or 0 once the delivery has had all its attempts
+func Backoff(policy repository.RetryPolicy, attempt int) time.Duration {
+	if attempt >= policy.MaxAttempts {
+		return 0
+	}
+	delay := policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	return delay
+}
+
+// Deliver makes one attempt at a delivery. The event is POSTed to the endpoint as JSON,
+// signed with the endpoint's secret at now, and the endpoint must accept it with a 2xx
+// response; anything else, including a redirect, is a failure.
+func Deliver(ctx context.Context, client *http.Client, d repository.DueWebhookDelivery, now time.Time) repository.WebhookAttempt {
+	body, err := json.Marshal(relay.Event{
+		Id:          d.Event.Id,
+		Position:    d.Event.Position,
+		Type:        d.Event.Type,
+		AggregateId: d.Event.AggregateId,
+		Payload:     d.Event.Payload,
+		CreatedAt:   d.Event.CreatedAt,
+	})
+	if err != nil {
+		return repository.WebhookAttempt{Error: fmt.Sprintf("error encoding event: %v", err)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader(body))
+	if err != nil {
+		return repository.WebhookAttempt{Error: fmt.Sprintf("error creating request: %v", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(d.Secret, now, body))
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.Id, 10))
+	req.Header.Set(EventTypeHeader, d.EventType)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return repository.WebhookAttempt{Error: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return repository.WebhookAttempt{StatusCode: resp.StatusCode}
+	}
+	msg := "endpoint responded with " + resp.Status
+	if snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLen)); len(bytes.TrimSpace(snippet)) > 0 {
+		msg += ": " + strings.TrimSpace(string(snippet))
+	}
+	return repository.WebhookAttempt{StatusCode: resp.StatusCode, Error: msg}
+}
+
+// Worker attempts due webhook deliveries. Several workers may run against the same database;
+// each delivery is attempted by one of them at a time.
+type Worker struct {
+	webhooks     *repository.WebhookRepository
+	client       *http.Client
+	retry        repository.RetryPolicy
+	pollInterval time.Duration
+	batchSize    int
+}
+
+// NewWorker creates a worker that makes its attempts with client, which should have a
+// timeout: a delivery is only kept from other workers for a minute longer than it
+func NewWorker(webhooks *repository.WebhookRepository, client *http.Client, retry repository.RetryPolicy, pollInterval time.Duration, batchSize int) *Worker {
+	return &Worker{webhooks: webhooks, client: client, retry: retry, pollInterval: pollInterval, batchSize: batchSize}
+}
+
+// Run attempts due deliveries until the context is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		n, err := w.DeliverDue(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to deliver webhooks", "error", err)
+		}
+
+		// A full batch means more deliveries may be due
+		var delay time.Duration
+		if err != nil || n < w.batchSize {
+			delay = w.pollInterval
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// DeliverDue attempts up to a batch of due deliveries in parallel and records the outcome of
+// each. It returns how many deliveries were attempted.
+func (w *Worker) DeliverDue(ctx context.Context) (int, error) {
+	due, err := w.webhooks.ClaimDueWebhookDeliveries(ctx, w.batchSize, w.client.Timeout+time.Minute)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func(d repository.DueWebhookDelivery) {
+			defer wg.Done()
+			w.attempt(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+func (w *Worker) attempt(ctx context.Context, d repository.DueWebhookDelivery) {
+	attempt := Deliver(ctx, w.client, d, time.Now())
+	if attempt.Error != "" {
+		attempt.RetryAfter = Backoff(w.retry, d.Attempts+1)
+		slog.WarnContext(ctx, "webhook delivery failed", "delivery_id", d.Id, "endpoint_id", d.EndpointId, "attempt", d.Attempts+1,
+			"retry_in", attempt.RetryAfter, "error", attempt.Error)
+	}
+	// If the outcome is not recorded, the delivery is attempted again once its claim runs out
+	if err := w.webhooks.RecordWebhookAttempt(ctx, d.Id, attempt); err != nil {
+		slog.ErrorContext(ctx, "failed to record webhook attempt", "delivery_id", d.Id, "error", err)
+	}
+}
+
+// FanoutSink is a relay sink that schedules a delivery of each ledger event to every webhook
+// endpoint that is sent it. The relay may hand it the same events more than once, but an
+// event is only ever scheduled once per endpoint.
+type FanoutSink struct {
+	webhooks *repository.WebhookRepository
+}
+
+func NewFanoutSink(webhooks *repository.WebhookRepository) *FanoutSink {
+	return &FanoutSink{webhooks: webhooks}
+}
+
+func (f *FanoutSink) Name() string {
+	return "webhook_endpoints"
+}
+
+func (f *FanoutSink) Publish(ctx context.Context, events []relay.Event) error {
+	ledgerEvents := make([]repository.LedgerEvent, len(events))
+	for i, e := range events {
+		ledgerEvents[i] = repository.LedgerEvent{Id: e.Id, Position: e.Position, Type: e.Type, AggregateId: e.AggregateId, Payload: e.Payload, CreatedAt: e.CreatedAt}
+	}
+	n, err := f.webhooks.ScheduleWebhookDeliveries(ctx, ledgerEvents)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		slog.DebugContext(ctx, "scheduled webhook deliveries", "count", n)
+	}
+	return nil
+}
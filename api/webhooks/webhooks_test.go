@@ -0,0 +1,106 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rasha-hantash/chariot-takehome/api/grpc/repository"
+	"github.com/rasha-hantash/chariot-takehome/api/relay"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDelivery(url string) repository.DueWebhookDelivery {
+	return repository.DueWebhookDelivery{
+		WebhookDelivery: repository.WebhookDelivery{Id: 7, EndpointId: "whk_1", EventId: 3, EventType: repository.EventTransactionCreated},
+		Url:             url,
+		Secret:          "whsec_test",
+		Event: repository.LedgerEvent{
+			Id:          3,
+			Position:    3,
+			Type:        repository.EventTransactionCreated,
+			AggregateId: "txn_1",
+			Payload:     json.RawMessage(`{"transaction_id":"txn_1"}`),
+			CreatedAt:   time.Now().UTC(),
+		},
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{"accepted", http.StatusOK, "", http.StatusOK, ""},
+		{"accepted without content", http.StatusNoContent, "", http.StatusNoContent, ""},
+		{"server error", http.StatusInternalServerError, " database is down\n", http.StatusInternalServerError,
+			"endpoint responded with 500 Internal Server Error: database is down"},
+		{"redirect", http.StatusFound, "", http.StatusFound, "endpoint responded with 302 Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			var received relay.Event
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				// The receiver can check the delivery came from us and was not tampered with
+				assert.NoError(t, Verify("whsec_test", r.Header.Get(SignatureHeader), body, 5*time.Minute, now))
+				assert.Equal(t, "7", r.Header.Get(DeliveryHeader))
+				assert.Equal(t, repository.EventTransactionCreated, r.Header.Get(EventTypeHeader))
+				assert.NoError(t, json.Unmarshal(body, &received))
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+			attempt := Deliver(context.Background(), client, testDelivery(server.URL), now)
+			assert.Equal(t, tt.wantStatus, attempt.StatusCode)
+			assert.Equal(t, tt.wantError, attempt.Error)
+			assert.Equal(t, "txn_1", received.AggregateId)
+			assert.JSONEq(t, `{"transaction_id":"txn_1"}`, string(received.Payload))
+		})
+	}
+
+	t.Run("unreachable endpoint", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		url := server.URL
+		server.Close()
+
+		attempt := Deliver(context.Background(), http.DefaultClient, testDelivery(url), time.Now())
+		assert.Zero(t, attempt.StatusCode)
+		assert.NotEmpty(t, attempt.Error)
+	})
+
+	t.Run("slow endpoint", func(t *testing.T) {
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		attempt := Deliver(context.Background(), &http.Client{Timeout: 50 * time.Millisecond}, testDelivery(server.URL), time.Now())
+		assert.Zero(t, attempt.StatusCode)
+		assert.Contains(t, attempt.Error, "Timeout")
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	policy := repository.RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	var delays []time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		delays = append(delays, Backoff(policy, attempt))
+	}
+	// The fifth failure is the last attempt, after which the delivery is dead
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 0}, delays)
+}
@@ -89,3 +89,257 @@ func (c *ApiClient) GetAccountBalance(ctx context.Context, req *pb.GetAccountBal
 	}
 	return resp, nil
 }
+
+func (c *ApiClient) CreateHold(ctx context.Context, req *pb.CreateHoldRequest) (*pb.Hold, error) {
+	resp, err := c.client.CreateHold(ctx, req)
+	if err != nil {
+		slog.Error("error creating hold", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) CaptureHold(ctx context.Context, req *pb.CaptureHoldRequest) (*pb.Hold, error) {
+	resp, err := c.client.CaptureHold(ctx, req)
+	if err != nil {
+		slog.Error("error capturing hold", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) VoidHold(ctx context.Context, req *pb.VoidHoldRequest) (*pb.Hold, error) {
+	resp, err := c.client.VoidHold(ctx, req)
+	if err != nil {
+		slog.Error("error voiding hold", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) ReverseTransaction(ctx context.Context, req *pb.ReverseTransactionRequest) (*pb.Transaction, error) {
+	resp, err := c.client.ReverseTransaction(ctx, req)
+	if err != nil {
+		slog.Error("error reversing transaction", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) PostJournalEntry(ctx context.Context, req *pb.PostJournalEntryRequest) (*pb.Transaction, error) {
+	resp, err := c.client.PostJournalEntry(ctx, req)
+	if err != nil {
+		slog.Error("error posting journal entry", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) FreezeAccount(ctx context.Context, req *pb.AccountStateChangeRequest) (*pb.Account, error) {
+	resp, err := c.client.FreezeAccount(ctx, req)
+	if err != nil {
+		slog.Error("error freezing account", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) UnfreezeAccount(ctx context.Context, req *pb.AccountStateChangeRequest) (*pb.Account, error) {
+	resp, err := c.client.UnfreezeAccount(ctx, req)
+	if err != nil {
+		slog.Error("error unfreezing account", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) CloseAccount(ctx context.Context, req *pb.AccountStateChangeRequest) (*pb.Account, error) {
+	resp, err := c.client.CloseAccount(ctx, req)
+	if err != nil {
+		slog.Error("error closing account", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) SetOverdraftLimit(ctx context.Context, req *pb.SetOverdraftLimitRequest) (*pb.Account, error) {
+	resp, err := c.client.SetOverdraftLimit(ctx, req)
+	if err != nil {
+		slog.Error("error setting overdraft limit", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	resp, err := c.client.GetUser(ctx, req)
+	if err != nil {
+		slog.Error("error getting user", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) GetUserByEmail(ctx context.Context, req *pb.GetUserByEmailRequest) (*pb.User, error) {
+	resp, err := c.client.GetUserByEmail(ctx, req)
+	if err != nil {
+		slog.Error("error getting user by email", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.User, error) {
+	resp, err := c.client.UpdateUser(ctx, req)
+	if err != nil {
+		slog.Error("error updating user", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	resp, err := c.client.ListUsers(ctx, req)
+	if err != nil {
+		slog.Error("error listing users", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) DeactivateUser(ctx context.Context, req *pb.DeactivateUserRequest) (*pb.User, error) {
+	resp, err := c.client.DeactivateUser(ctx, req)
+	if err != nil {
+		slog.Error("error deactivating user", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.Account, error) {
+	resp, err := c.client.GetAccount(ctx, req)
+	if err != nil {
+		slog.Error("error getting account", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	resp, err := c.client.ListAccounts(ctx, req)
+	if err != nil {
+		slog.Error("error listing accounts", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) GetTransaction(ctx context.Context, req *pb.GetTransactionRequest) (*pb.Transaction, error) {
+	resp, err := c.client.GetTransaction(ctx, req)
+	if err != nil {
+		slog.Error("error getting transaction", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+// WatchTransactions opens a transaction stream; errors from the API service, such as an
+// unknown account, are returned by the stream's first Recv
+func (c *ApiClient) WatchTransactions(ctx context.Context, req *pb.WatchTransactionsRequest) (pb.ApiService_WatchTransactionsClient, error) {
+	stream, err := c.client.WatchTransactions(ctx, req)
+	if err != nil {
+		slog.Error("error watching transactions", "error", err.Error())
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (c *ApiClient) WatchBalance(ctx context.Context, req *pb.WatchBalanceRequest) (pb.ApiService_WatchBalanceClient, error) {
+	stream, err := c.client.WatchBalance(ctx, req)
+	if err != nil {
+		slog.Error("error watching balance", "error", err.Error())
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (c *ApiClient) CreateWebhookEndpoint(ctx context.Context, req *pb.CreateWebhookEndpointRequest) (*pb.WebhookEndpoint, error) {
+	resp, err := c.client.CreateWebhookEndpoint(ctx, req)
+	if err != nil {
+		slog.Error("error creating webhook endpoint", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) ListWebhookEndpoints(ctx context.Context, req *pb.ListWebhookEndpointsRequest) (*pb.ListWebhookEndpointsResponse, error) {
+	resp, err := c.client.ListWebhookEndpoints(ctx, req)
+	if err != nil {
+		slog.Error("error listing webhook endpoints", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) DeleteWebhookEndpoint(ctx context.Context, req *pb.DeleteWebhookEndpointRequest) (*pb.WebhookEndpoint, error) {
+	resp, err := c.client.DeleteWebhookEndpoint(ctx, req)
+	if err != nil {
+		slog.Error("error deleting webhook endpoint", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	resp, err := c.client.ListWebhookDeliveries(ctx, req)
+	if err != nil {
+		slog.Error("error listing webhook deliveries", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) RedeliverWebhook(ctx context.Context, req *pb.RedeliverWebhookRequest) (*pb.WebhookDelivery, error) {
+	resp, err := c.client.RedeliverWebhook(ctx, req)
+	if err != nil {
+		slog.Error("error redelivering webhook", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) AddPaymentMethod(ctx context.Context, req *pb.AddPaymentMethodRequest) (*pb.PaymentMethod, error) {
+	resp, err := c.client.AddPaymentMethod(ctx, req)
+	if err != nil {
+		slog.Error("error adding payment method", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) ListPaymentMethods(ctx context.Context, req *pb.ListPaymentMethodsRequest) (*pb.ListPaymentMethodsResponse, error) {
+	resp, err := c.client.ListPaymentMethods(ctx, req)
+	if err != nil {
+		slog.Error("error listing payment methods", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) VerifyPaymentMethod(ctx context.Context, req *pb.PaymentMethodRequest) (*pb.PaymentMethod, error) {
+	resp, err := c.client.VerifyPaymentMethod(ctx, req)
+	if err != nil {
+		slog.Error("error verifying payment method", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ApiClient) RemovePaymentMethod(ctx context.Context, req *pb.PaymentMethodRequest) (*pb.PaymentMethod, error) {
+	resp, err := c.client.RemovePaymentMethod(ctx, req)
+	if err != nil {
+		slog.Error("error removing payment method", "error", err.Error())
+		return nil, err
+	}
+	return resp, nil
+}
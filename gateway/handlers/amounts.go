@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/rasha-hantash/chariot-takehome/api/pkgs/money"
+)
+
+// decodeAmounts decodes a JSON request body into req. Besides minor units,
+// {"units": 1055, "currency": "USD"}, an amount may be given as a decimal string in major
+// units, {"value": "10.55", "currency": "USD"}. The decimal is converted exactly, and an
+// amount more precise than its currency's minor unit is rejected rather than rounded.
+func decodeAmounts(r *http.Request, req interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	// Keep numbers as they were sent so that large units survive the round trip
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return err
+	}
+	body, err := convertAmounts("", body)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(bytes.NewReader(b)).Decode(req)
+}
+
+// convertAmounts replaces the decimal value of every amount in a decoded JSON body with its
+// minor units. field is the path to the body, used in errors.
+func convertAmounts(field string, body interface{}) (interface{}, error) {
+	switch v := body.(type) {
+	case map[string]interface{}:
+		if value, ok := v["value"]; ok {
+			return convertAmount(field, v, value)
+		}
+		for key, nested := range v {
+			converted, err := convertAmounts(join(field, key), nested)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = converted
+		}
+	case []interface{}:
+		for i, nested := range v {
+			converted, err := convertAmounts(fmt.Sprintf("%s[%d]", field, i), nested)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+	}
+	return body, nil
+}
+
+func convertAmount(field string, amount map[string]interface{}, value interface{}) (interface{}, error) {
+	decimal, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s.value: must be a decimal string such as \"10.55\"", field)
+	}
+	if _, ok := amount["units"]; ok {
+		return nil, fmt.Errorf("%s: only one of units and value may be set", field)
+	}
+	code, _ := amount["currency"].(string)
+	m, err := money.Parse(decimal, code)
+	if err != nil {
+		return nil, fmt.Errorf("%s.value: %w", field, err)
+	}
+	return map[string]interface{}{"units": json.Number(fmt.Sprint(m.Units)), "currency": m.Currency}, nil
+}
+
+func join(field, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
+
+// writeDecodeError reports a request body that could not be decoded
+func writeDecodeError(w http.ResponseWriter, err error) {
+	code := "INVALID_REQUEST"
+	if errors.Is(err, money.ErrInvalidAmount) || errors.Is(err, money.ErrNegativeAmount) || errors.Is(err, money.ErrSubMinorUnit) {
+		code = "INVALID_AMOUNT"
+	}
+	writeErrorCode(w, http.StatusBadRequest, code, err.Error())
+}
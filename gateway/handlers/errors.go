@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorResponse is the body of every error response. Code is a stable, machine-readable
+// reason such as INSUFFICIENT_FUNDS; Message is meant for humans and may change.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Violations lists what is wrong with each field of an invalid request
+	Violations []FieldViolation `json:"violations,omitempty"`
+}
+
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// httpStatuses maps gRPC status codes to the HTTP status they are reported with
+var httpStatuses = map[codes.Code]int{
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.FailedPrecondition: http.StatusConflict,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+// reasonStatuses overrides the HTTP status for specific error reasons
+var reasonStatuses = map[string]int{
+	"INSUFFICIENT_FUNDS": http.StatusPaymentRequired,
+}
+
+// codeNames are the fallback error codes for statuses without an ErrorInfo reason
+var codeNames = map[codes.Code]string{
+	codes.Canceled:           "CANCELED",
+	codes.InvalidArgument:    "INVALID_ARGUMENT",
+	codes.DeadlineExceeded:   "DEADLINE_EXCEEDED",
+	codes.NotFound:           "NOT_FOUND",
+	codes.AlreadyExists:      "ALREADY_EXISTS",
+	codes.PermissionDenied:   "PERMISSION_DENIED",
+	codes.ResourceExhausted:  "RESOURCE_EXHAUSTED",
+	codes.FailedPrecondition: "FAILED_PRECONDITION",
+	codes.Aborted:            "ABORTED",
+	codes.Unimplemented:      "UNIMPLEMENTED",
+	codes.Internal:           "INTERNAL",
+	codes.Unavailable:        "UNAVAILABLE",
+	codes.Unauthenticated:    "UNAUTHENTICATED",
+}
+
+// writeError translates an error from the API service into an HTTP status and error body
+func writeError(w http.ResponseWriter, err error) {
+	httpStatus, body := errorBody(err)
+	writeErrorBody(w, httpStatus, body)
+}
+
+// errorBody returns the HTTP status and error body an error from the API service is
+// reported with
+func errorBody(err error) (int, ErrorBody) {
+	st := status.Convert(err)
+
+	code, ok := codeNames[st.Code()]
+	if !ok {
+		code = "UNKNOWN"
+	}
+	var violations []FieldViolation
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			code = d.Reason
+		case *errdetails.BadRequest:
+			for _, fv := range d.FieldViolations {
+				violations = append(violations, FieldViolation{Field: fv.Field, Description: fv.Description})
+			}
+		}
+	}
+
+	httpStatus, ok := reasonStatuses[code]
+	if !ok {
+		httpStatus, ok = httpStatuses[st.Code()]
+	}
+	if !ok {
+		httpStatus = http.StatusInternalServerError
+	}
+	return httpStatus, ErrorBody{Code: code, Message: st.Message(), Violations: violations}
+}
+
+// writeErrorCode writes an error body with the given HTTP status
+func writeErrorCode(w http.ResponseWriter, httpStatus int, code, message string) {
+	writeErrorBody(w, httpStatus, ErrorBody{Code: code, Message: message})
+}
+
+func writeErrorBody(w http.ResponseWriter, httpStatus int, body ErrorBody) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: body})
+}
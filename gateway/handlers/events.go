@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	pb "github.com/rasha-hantash/chariot-takehome/api/grpc/proto"
+	client "github.com/rasha-hantash/chariot-takehome/gateway/grpcClient"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// sseHeartbeatInterval is how often a comment is sent on an idle event stream so that
+// proxies do not close the connection
+const sseHeartbeatInterval = 15 * time.Second
+
+// sseEvent is a Server-Sent Event; an empty id leaves the client's last event id unchanged
+type sseEvent struct {
+	id    string
+	event string
+	data  interface{}
+}
+
+// WatchTransactionsHandler streams an account's transactions as Server-Sent Events. An
+// EventSource that reconnects sends the id of the last event it received as Last-Event-ID,
+// which takes precedence over from_cursor so the stream resumes where it broke off.
+func WatchTransactionsHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountID := r.URL.Query().Get("account_id")
+		if accountID == "" {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "missing required query parameter: account_id")
+			return
+		}
+		req := &pb.WatchTransactionsRequest{AccountId: accountID}
+
+		cursor := r.Header.Get("Last-Event-ID")
+		if cursor == "" {
+			cursor = r.URL.Query().Get("from_cursor")
+		}
+		if cursor != "" {
+			c, err := strconv.ParseInt(cursor, 10, 64)
+			if err != nil {
+				slog.ErrorContext(ctx, "error parsing cursor", "error", err, "cursor", cursor)
+				writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid from_cursor value, expected an integer")
+				return
+			}
+			req.FromCursor = c
+		}
+
+		// The stream lasts as long as the client stays connected
+		stream, err := grpcClient.WatchTransactions(r.Context(), req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		streamEvents(w, r, func() (sseEvent, error) {
+			e, err := stream.Recv()
+			if err != nil {
+				return sseEvent{}, err
+			}
+			id := strconv.FormatInt(e.Cursor, 10)
+			if e.Transaction == nil {
+				// The replay has caught up
+				return sseEvent{id: id, event: "ready", data: struct {
+					Cursor int64 `json:"cursor"`
+				}{e.Cursor}}, nil
+			}
+			return sseEvent{id: id, event: e.Type, data: e.Transaction}, nil
+		})
+	}
+}
+
+// WatchBalanceHandler streams an account's balance as Server-Sent Events, starting with the
+// current balance and then every time it changes
+func WatchBalanceHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountID := r.URL.Query().Get("account_id")
+		if accountID == "" {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "missing required query parameter: account_id")
+			return
+		}
+
+		stream, err := grpcClient.WatchBalance(r.Context(), &pb.WatchBalanceRequest{AccountId: accountID})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		streamEvents(w, r, func() (sseEvent, error) {
+			balance, err := stream.Recv()
+			if err != nil {
+				return sseEvent{}, err
+			}
+			return sseEvent{event: "balance", data: balance}, nil
+		})
+	}
+}
+
+// streamEvents writes what recv returns to the client as Server-Sent Events until either side
+// goes away. An error before the first event is written as an ordinary error response; once
+// the stream has started, an error ends it with an "error" event carrying the error body.
+func streamEvents(w http.ResponseWriter, r *http.Request, recv func() (sseEvent, error)) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorCode(w, http.StatusInternalServerError, "INTERNAL", "streaming is not supported")
+		return
+	}
+
+	first, err := recv()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := writeEvent(w, first); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	// Receive in the background so heartbeats can be sent while the stream is idle. Recv
+	// returns once the request's context is done, so the goroutine does not outlive it.
+	events := make(chan sseEvent)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			e, err := recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case events <- e:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events:
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case err := <-errs:
+			if !errors.Is(err, io.EOF) && status.Code(err) != codes.Canceled {
+				slog.ErrorContext(r.Context(), "event stream failed", "error", err)
+				_, body := errorBody(err)
+				writeEvent(w, sseEvent{event: "error", data: ErrorResponse{Error: body}})
+				flusher.Flush()
+			}
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w io.Writer, e sseEvent) error {
+	data, err := json.Marshal(e.data)
+	if err != nil {
+		return fmt.Errorf("error encoding %s event: %w", e.event, err)
+	}
+	if e.id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", e.id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.event, data)
+	return err
+}
@@ -6,28 +6,25 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
-	"sync"
+	"time"
 
+	"github.com/gorilla/mux"
 	pb "github.com/rasha-hantash/chariot-takehome/api/grpc/proto"
 	client "github.com/rasha-hantash/chariot-takehome/gateway/grpcClient"
-)
-
-var (
-	idempotencyKeys = make(map[string]bool)
-	mu              sync.Mutex
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func CreateUserHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req pb.CreateUserRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
 			return
 		}
 
 		user, err := grpcClient.CreateUser(ctx, &req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 
@@ -38,14 +35,14 @@ func CreateUserHandler(ctx context.Context, grpcClient *client.ApiClient) http.H
 func CreateAccountHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req pb.CreateAccountRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if err := decodeAmounts(r, &req); err != nil {
+			writeDecodeError(w, err)
 			return
 		}
 
 		account, err := grpcClient.CreateAccount(ctx, &req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 
@@ -56,29 +53,17 @@ func CreateAccountHandler(ctx context.Context, grpcClient *client.ApiClient) htt
 func DepositFundsHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req pb.DepositFundsRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		mu.Lock()
-		defer mu.Unlock()
-
-		// Check for idempotency key
-		if idempotencyKeys[req.IdempotencyKey] {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"message": "Duplicate request detected"}`))
+		if err := decodeAmounts(r, &req); err != nil {
+			writeDecodeError(w, err)
 			return
 		}
 
 		transaction, err := grpcClient.DepositFunds(ctx, &req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 
-		idempotencyKeys[req.IdempotencyKey] = true
-
 		json.NewEncoder(w).Encode(transaction)
 	}
 }
@@ -86,18 +71,8 @@ func DepositFundsHandler(ctx context.Context, grpcClient *client.ApiClient) http
 func WithdrawFundsHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req pb.WithdrawFundsRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		mu.Lock()
-		defer mu.Unlock()
-
-		// Check for idempotency key
-		if idempotencyKeys[req.IdempotencyKey] {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"message": "Duplicate request detected"}`))
+		if err := decodeAmounts(r, &req); err != nil {
+			writeDecodeError(w, err)
 			return
 		}
 
@@ -105,13 +80,10 @@ func WithdrawFundsHandler(ctx context.Context, grpcClient *client.ApiClient) htt
 		transaction, err := grpcClient.WithdrawFunds(ctx, &req)
 		if err != nil {
 			// Send error response
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 
-		// Mark the idempotency key as used
-		idempotencyKeys[req.IdempotencyKey] = true
-
 		// Encode and send the transaction with status 200
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK) // Explicitly setting the status code to 200
@@ -125,31 +97,18 @@ func WithdrawFundsHandler(ctx context.Context, grpcClient *client.ApiClient) htt
 func TransferFundsHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req pb.TransferFundsRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		mu.Lock()
-		defer mu.Unlock()
-
-		// Check for idempotency key
-		if idempotencyKeys[req.IdempotencyKey] {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte(`{"message": "Duplicate request detected"}`))
+		if err := decodeAmounts(r, &req); err != nil {
+			writeDecodeError(w, err)
 			return
 		}
 
 		transaction, err := grpcClient.TransferFunds(ctx, &req)
 		if err != nil {
 			// Send error response
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 
-		// Mark the idempotency key as used
-		idempotencyKeys[req.IdempotencyKey] = true
-
 		// Encode and send the transaction with status 200
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK) // Explicitly setting the status code to 200
@@ -162,30 +121,75 @@ func TransferFundsHandler(ctx context.Context, grpcClient *client.ApiClient) htt
 
 func ListTransactionsHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		accountID := r.URL.Query().Get("account_id")
-		cursor := r.URL.Query().Get("cursor")
-		limit := r.URL.Query().Get("limit")
-		limitInt, err := strconv.Atoi(limit)
-		if err != nil {
-			slog.ErrorContext(ctx, "error parsing limit", "error", err, "limit", limit)
-			http.Error(w, "invalid limit value", http.StatusBadRequest)
-			return
-		}
+		query := r.URL.Query()
+		accountID := query.Get("account_id")
 
 		// Validate required query parameters
 		if accountID == "" {
-			http.Error(w, "missing required query parameters: account_id and at_time", http.StatusBadRequest)
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "missing required query parameter: account_id")
 			return
 		}
 		req := pb.ListTransactionsRequest{
-			AccountId: accountID,
-			Cursor:    cursor,
-			Limit:     int32(limitInt),
+			AccountId:             accountID,
+			PageToken:             query.Get("page_token"),
+			Direction:             query.Get("direction"),
+			Status:                query.Get("status"),
+			CounterpartyAccountId: query.Get("counterparty_account_id"),
+			CreatedBy:             query.Get("created_by"),
+			Descending:            query.Get("order") == "desc",
+			IncludeLegs:           query.Get("include_legs") == "true",
+		}
+
+		// The limit is optional; the API falls back to its default page size
+		if limit := query.Get("limit"); limit != "" {
+			limitInt, err := strconv.Atoi(limit)
+			if err != nil {
+				slog.ErrorContext(ctx, "error parsing limit", "error", err, "limit", limit)
+				writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid limit value")
+				return
+			}
+			req.Limit = int32(limitInt)
+		}
+
+		// from and to bound the creation time of the transactions, from inclusive and to exclusive
+		for _, param := range []struct {
+			name string
+			dst  **timestamppb.Timestamp
+		}{{"from", &req.From}, {"to", &req.To}} {
+			value := query.Get(param.name)
+			if value == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				slog.ErrorContext(ctx, "error parsing "+param.name, "error", err, param.name, value)
+				writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid "+param.name+" value, expected an RFC 3339 timestamp")
+				return
+			}
+			*param.dst = timestamppb.New(t)
+		}
+
+		// min_amount and max_amount are in minor units of the currency query parameter
+		for _, param := range []struct {
+			name string
+			dst  **pb.Money
+		}{{"min_amount", &req.MinAmount}, {"max_amount", &req.MaxAmount}} {
+			value := query.Get(param.name)
+			if value == "" {
+				continue
+			}
+			units, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				slog.ErrorContext(ctx, "error parsing "+param.name, "error", err, param.name, value)
+				writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid "+param.name+" value, expected minor units")
+				return
+			}
+			*param.dst = &pb.Money{Units: units, Currency: query.Get("currency")}
 		}
 
 		transactions, err := grpcClient.ListTransactions(ctx, &req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 
@@ -198,29 +202,58 @@ func ListTransactionsHandler(ctx context.Context, grpcClient *client.ApiClient)
 	}
 }
 
+// GetTransactionHandler returns a transaction with its legs, reversals and history
+func GetTransactionHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		transaction, err := grpcClient.GetTransaction(ctx, &pb.GetTransactionRequest{TransactionId: mux.Vars(r)["id"]})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(transaction); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 // GetAccountBalanceHandler handles the request for getting account balance
 func GetAccountBalanceHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Extract query parameters
 		accountID := r.URL.Query().Get("account_id")
-		slog.Info("query param", "accountID", accountID)
+		atTime := r.URL.Query().Get("at_time")
+		slog.Info("query param", "accountID", accountID, "atTime", atTime)
 
 		// Validate required query parameters
 		if accountID == "" {
-			http.Error(w, "missing required query parameters: account_id and at_time", http.StatusBadRequest)
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "missing required query parameter: account_id")
 			return
 		}
 
 		// Create the request object
 		req := pb.GetAccountBalanceRequest{
 			AccountId: accountID,
-			// AtTime:    atTime,
+		}
+
+		// at_time is optional, when it is omitted the current balance is returned
+		if atTime != "" {
+			t, err := time.Parse(time.RFC3339, atTime)
+			if err != nil {
+				slog.ErrorContext(ctx, "error parsing at_time", "error", err, "at_time", atTime)
+				writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid at_time value, expected an RFC 3339 timestamp")
+				return
+			}
+			req.AtTime = timestamppb.New(t)
 		}
 
 		// Call the gRPC client
 		balance, err := grpcClient.GetAccountBalance(ctx, &req)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
 
@@ -233,3 +266,389 @@ func GetAccountBalanceHandler(ctx context.Context, grpcClient *client.ApiClient)
 		}
 	}
 }
+
+func CreateHoldHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.CreateHoldRequest
+		if err := decodeAmounts(r, &req); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+
+		hold, err := grpcClient.CreateHold(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(hold); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func CaptureHoldHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.CaptureHoldRequest
+		if err := decodeAmounts(r, &req); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+
+		hold, err := grpcClient.CaptureHold(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(hold); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func VoidHoldHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.VoidHoldRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		hold, err := grpcClient.VoidHold(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(hold); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func ReverseTransactionHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.ReverseTransactionRequest
+		if err := decodeAmounts(r, &req); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+
+		txn, err := grpcClient.ReverseTransaction(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(txn); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func PostJournalEntryHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.PostJournalEntryRequest
+		if err := decodeAmounts(r, &req); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+
+		txn, err := grpcClient.PostJournalEntry(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(txn); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func FreezeAccountHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.AccountStateChangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		account, err := grpcClient.FreezeAccount(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(account); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func UnfreezeAccountHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.AccountStateChangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		account, err := grpcClient.UnfreezeAccount(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(account); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func CloseAccountHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.AccountStateChangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		account, err := grpcClient.CloseAccount(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(account); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func SetOverdraftLimitHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.SetOverdraftLimitRequest
+		if err := decodeAmounts(r, &req); err != nil {
+			writeDecodeError(w, err)
+			return
+		}
+
+		account, err := grpcClient.SetOverdraftLimit(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(account); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetUserHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.URL.Query().Get("user_id")
+		if userID == "" {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "missing required query parameter: user_id")
+			return
+		}
+
+		user, err := grpcClient.GetUser(ctx, &pb.GetUserRequest{UserId: userID})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(user); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetUserByEmailHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email := r.URL.Query().Get("email")
+		if email == "" {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "missing required query parameter: email")
+			return
+		}
+
+		user, err := grpcClient.GetUserByEmail(ctx, &pb.GetUserByEmailRequest{Email: email})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(user); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func UpdateUserHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.UpdateUserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		user, err := grpcClient.UpdateUser(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(user); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func ListUsersHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := pb.ListUsersRequest{Cursor: r.URL.Query().Get("cursor")}
+		// The limit is optional; the API falls back to its default page size
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			limitInt, err := strconv.Atoi(limit)
+			if err != nil {
+				slog.ErrorContext(ctx, "error parsing limit", "error", err, "limit", limit)
+				writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid limit value")
+				return
+			}
+			req.Limit = int32(limitInt)
+		}
+
+		users, err := grpcClient.ListUsers(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(users); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func DeactivateUserHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.DeactivateUserRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		user, err := grpcClient.DeactivateUser(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(user); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetAccountHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountID := r.URL.Query().Get("account_id")
+		if accountID == "" {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "missing required query parameter: account_id")
+			return
+		}
+
+		account, err := grpcClient.GetAccount(ctx, &pb.GetAccountRequest{AccountId: accountID})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(account); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func ListAccountsHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		req := pb.ListAccountsRequest{
+			UserId:       query.Get("user_id"),
+			AccountType:  query.Get("account_type"),
+			AccountState: query.Get("account_state"),
+			Cursor:       query.Get("cursor"),
+		}
+		// The limit is optional; the API falls back to its default page size
+		if limit := query.Get("limit"); limit != "" {
+			limitInt, err := strconv.Atoi(limit)
+			if err != nil {
+				slog.ErrorContext(ctx, "error parsing limit", "error", err, "limit", limit)
+				writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid limit value")
+				return
+			}
+			req.Limit = int32(limitInt)
+		}
+
+		accounts, err := grpcClient.ListAccounts(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(accounts); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
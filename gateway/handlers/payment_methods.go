@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	pb "github.com/rasha-hantash/chariot-takehome/api/grpc/proto"
+	client "github.com/rasha-hantash/chariot-takehome/gateway/grpcClient"
+)
+
+func AddPaymentMethodHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.AddPaymentMethodRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		method, err := grpcClient.AddPaymentMethod(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(method); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func ListPaymentMethodsHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := pb.ListPaymentMethodsRequest{UserId: r.URL.Query().Get("user_id")}
+
+		methods, err := grpcClient.ListPaymentMethods(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(methods); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func VerifyPaymentMethodHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.PaymentMethodRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		method, err := grpcClient.VerifyPaymentMethod(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(method); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func RemovePaymentMethodHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.PaymentMethodRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		method, err := grpcClient.RemovePaymentMethod(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(method); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	pb "github.com/rasha-hantash/chariot-takehome/api/grpc/proto"
+	client "github.com/rasha-hantash/chariot-takehome/gateway/grpcClient"
+)
+
+func CreateWebhookEndpointHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.CreateWebhookEndpointRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		endpoint, err := grpcClient.CreateWebhookEndpoint(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(endpoint); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func ListWebhookEndpointsHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := pb.ListWebhookEndpointsRequest{UserId: r.URL.Query().Get("user_id")}
+
+		endpoints, err := grpcClient.ListWebhookEndpoints(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(endpoints); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func DeleteWebhookEndpointHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.DeleteWebhookEndpointRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		endpoint, err := grpcClient.DeleteWebhookEndpoint(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(endpoint); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func ListWebhookDeliveriesHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		req := pb.ListWebhookDeliveriesRequest{
+			EndpointId:    query.Get("endpoint_id"),
+			UserId:        query.Get("user_id"),
+			DeliveryState: query.Get("delivery_state"),
+		}
+		// The cursor and limit are optional; the API starts at the newest delivery and falls
+		// back to its default page size
+		if cursor := query.Get("cursor"); cursor != "" {
+			cursorInt, err := strconv.ParseInt(cursor, 10, 64)
+			if err != nil {
+				slog.ErrorContext(ctx, "error parsing cursor", "error", err, "cursor", cursor)
+				writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid cursor value")
+				return
+			}
+			req.Cursor = cursorInt
+		}
+		if limit := query.Get("limit"); limit != "" {
+			limitInt, err := strconv.Atoi(limit)
+			if err != nil {
+				slog.ErrorContext(ctx, "error parsing limit", "error", err, "limit", limit)
+				writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid limit value")
+				return
+			}
+			req.Limit = int32(limitInt)
+		}
+
+		deliveries, err := grpcClient.ListWebhookDeliveries(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(deliveries); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func RedeliverWebhookHandler(ctx context.Context, grpcClient *client.ApiClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req pb.RedeliverWebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorCode(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+			return
+		}
+
+		delivery, err := grpcClient.RedeliverWebhook(ctx, &req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(delivery); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -57,7 +57,35 @@ func main() {
 	router.HandleFunc("/withdraw_funds", h.WithdrawFundsHandler(ctx, grpcClient)).Methods("POST")
 	router.HandleFunc("/transfer_funds", h.TransferFundsHandler(ctx, grpcClient)).Methods("POST")
 	router.HandleFunc("/list_transactions", h.ListTransactionsHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/transactions/{id}", h.GetTransactionHandler(ctx, grpcClient)).Methods("GET")
 	router.HandleFunc("/get_account_balance", h.GetAccountBalanceHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/watch_transactions", h.WatchTransactionsHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/watch_balance", h.WatchBalanceHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/create_webhook_endpoint", h.CreateWebhookEndpointHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/list_webhook_endpoints", h.ListWebhookEndpointsHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/delete_webhook_endpoint", h.DeleteWebhookEndpointHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/list_webhook_deliveries", h.ListWebhookDeliveriesHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/redeliver_webhook", h.RedeliverWebhookHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/add_payment_method", h.AddPaymentMethodHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/list_payment_methods", h.ListPaymentMethodsHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/verify_payment_method", h.VerifyPaymentMethodHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/remove_payment_method", h.RemovePaymentMethodHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/create_hold", h.CreateHoldHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/capture_hold", h.CaptureHoldHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/void_hold", h.VoidHoldHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/reverse_transaction", h.ReverseTransactionHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/post_journal_entry", h.PostJournalEntryHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/freeze_account", h.FreezeAccountHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/unfreeze_account", h.UnfreezeAccountHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/close_account", h.CloseAccountHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/set_overdraft_limit", h.SetOverdraftLimitHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/get_user", h.GetUserHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/get_user_by_email", h.GetUserByEmailHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/update_user", h.UpdateUserHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/list_users", h.ListUsersHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/deactivate_user", h.DeactivateUserHandler(ctx, grpcClient)).Methods("POST")
+	router.HandleFunc("/get_account", h.GetAccountHandler(ctx, grpcClient)).Methods("GET")
+	router.HandleFunc("/list_accounts", h.ListAccountsHandler(ctx, grpcClient)).Methods("GET")
 
 	log.Println("Gateway server listening on :8080")
 	log.Fatal(http.ListenAndServe(":8080", router))
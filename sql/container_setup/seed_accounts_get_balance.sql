@@ -8,8 +8,12 @@ INSERT INTO transactions (id, amount, status) VALUES
     ('txn_1', 500, 'in_process'),
     ('txn_2', 50, 'paid');
 
-INSERT INTO ledger_entries (id, transaction_id, account_id, direction, amount) VALUES 
-    ('le_2', 'txn_1',  'acct_2', 'debit', 500),
-    ('le_1', 'txn_1',  'acct_1', 'credit', 500),
-    ('le_3', 'txn_2',  'acct_1', 'debit', 50),
-    ('le_4', 'txn_2', 'acct_2', 'credit', 50);
+INSERT INTO ledger_entries (id, transaction_id, account_id, direction, amount, created_at) VALUES 
+    ('le_2', 'txn_1',  'acct_2', 'debit', 500, '2024-01-15 12:00:00+00'),
+    ('le_1', 'txn_1',  'acct_1', 'credit', 500, '2024-01-15 12:00:00+00'),
+    ('le_3', 'txn_2',  'acct_1', 'debit', 50, '2024-02-10 09:30:00+00'),
+    ('le_4', 'txn_2', 'acct_2', 'credit', 50, '2024-02-10 09:30:00+00');
+
+
+-- Bring the account_balances projection in line with the seeded ledger entries
+SELECT rebuild_account_balances();
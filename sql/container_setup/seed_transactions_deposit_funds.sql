@@ -1,14 +1,15 @@
 -- Seed users (assuming a users table exists)
-INSERT INTO users (id, email, name) VALUES
-('usr_1', 'hello@gmail.com', 'User 1'),
-('usr_2', 'hello+2@gmail.com','User 2'),
-('usr_3', 'hello+3@gmail.com', 'User 3');
+-- usr_2's external and usr_3's internal accounts do not exist
+INSERT INTO users (id, email, name, int_ledger_account_id, ext_ledger_account_id) VALUES
+('usr_1', 'hello@gmail.com', 'User 1', 'acct_1', 'acct_2'),
+('usr_2', 'hello+2@gmail.com','User 2', 'acct_4', 'acct_3'),
+('usr_3', 'hello+3@gmail.com', 'User 3', 'acct_6', 'acct_5');
 
 INSERT INTO accounts (id, account_state, account_type) VALUES 
     ('acct_1', 'open', 'internal'), -- user-1 internal account 
     ('acct_2', 'open', 'external'),
-    ('acct_4', 'open', 'external'),-- user-1 external account (ex: bank account)
-    ('acct_5', 'open', 'internal'); -- user-1 external account (ex: bank account)
+    ('acct_4', 'open', 'internal'), -- user-2 internal account
+    ('acct_5', 'open', 'external'); -- user-3 external account (ex: bank account)
 
 
 -- Seed payment methods for successful deposit
@@ -55,4 +55,7 @@ END $$;
 
 -- The final balances are always the same for a given number of transactions (as long as it's a multiple of 3 plus 2).
 -- acct_1 and acct_2 each participate in 33 transactions.
--- acct_3 participates in 34 transactions.
\ No newline at end of file
+-- acct_3 participates in 34 transactions.
+
+-- Bring the account_balances projection in line with the seeded ledger entries
+SELECT rebuild_account_balances();
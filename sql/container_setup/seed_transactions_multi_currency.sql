@@ -0,0 +1,25 @@
+INSERT INTO users (id, email, name, int_ledger_account_id, ext_ledger_account_id) VALUES
+('usr_1', 'hello+1@gmail.com', 'User 1', 'acct_1', 'acct_4'),
+('usr_2', 'hello+2@gmail.com', 'User 2', 'acct_2', NULL),
+('usr_3', 'hello+3@gmail.com', 'User 3', 'acct_3', 'acct_5');
+
+INSERT INTO accounts (id, user_id, account_state, account_type, currency) VALUES
+('acct_1', 'usr_1', 'open', 'debit', 'USD'), -- funded USD account
+('acct_2', 'usr_1', 'open', 'debit', 'USD'),
+('acct_3', 'usr_1', 'open', 'debit', 'EUR'), -- EUR account, cannot be paired with the USD accounts
+('acct_4', 'usr_1', 'open', 'credit', 'USD'),
+('acct_5', 'usr_1', 'open', 'credit', 'EUR');
+
+INSERT INTO transactions (id, amount, currency, status) VALUES
+('txn_1', 10000, 'USD', 'success'),
+('txn_2', 10000, 'EUR', 'success');
+
+INSERT INTO ledger_entries (id, transaction_id, account_id, amount, currency, exponent, direction) VALUES
+('le_1', 'txn_1', 'acct_4', 10000, 'USD', 2, 'debit'),
+('le_2', 'txn_1', 'acct_1', 10000, 'USD', 2, 'credit'),
+('le_3', 'txn_2', 'acct_5', 10000, 'EUR', 2, 'debit'),
+('le_4', 'txn_2', 'acct_3', 10000, 'EUR', 2, 'credit');
+
+
+-- Bring the account_balances projection in line with the seeded ledger entries
+SELECT rebuild_account_balances();
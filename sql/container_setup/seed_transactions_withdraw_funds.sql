@@ -1,8 +1,9 @@
 -- Insert users
-INSERT INTO users (id, email, name) VALUES
-('usr_1', 'hello+1@gmail.com', 'User 1'),
-('usr_2','hello+2@gmail.com', 'User 2'),
-('usr_3', 'hello+3@gmail.com', 'User 3');
+-- usr_3's external account does not exist
+INSERT INTO users (id, email, name, int_ledger_account_id, ext_ledger_account_id) VALUES
+('usr_1', 'hello+1@gmail.com', 'User 1', 'acct_1', 'acct_2'),
+('usr_2','hello+2@gmail.com', 'User 2', 'acct_3', 'acct_7'),
+('usr_3', 'hello+3@gmail.com', 'User 3', 'acct_5', 'acct_6');
 
 -- Insert accounts
 -- Assuming accounts table has columns for account ID, user ID, and balance
@@ -10,7 +11,8 @@ INSERT INTO accounts (id, user_id, account_state, account_type) VALUES
 ('acct_1', 'usr_1', 'open', 'debit'), -- Sufficient funds for withdrawal
 ('acct_2', 'usr_1', 'open', 'credit'),   -- Target account for successful withdrawal
 ('acct_3', 'usr_2', 'open','debit'),  -- Insufficient funds
-('acct_5', 'usr_3', 'open', 'credit'); -- Exists but credit account does not
+('acct_5', 'usr_3', 'open', 'credit'), -- Exists but credit account does not
+('acct_7', 'usr_2', 'open', 'credit');
 
 -- Note: acct-4 and acct_6 are intentionally omitted to simulate "account does not exist" scenarios
 
@@ -32,4 +34,7 @@ INSERT INTO ledger_entries (id, transaction_id, account_id, amount, direction) V
 ('le_3', 'txn_2', 'acct_1', 50, 'credit'), -- Debit from acct_1
 ('le_4', 'txn_2', 'acct_2', 50, 'debit'); -- Credit to acct_2
 
--- Note: For "insufficient funds" and "account does not exist" scenarios, ledger entries are not created as these transactions would fail validation checks before affecting account balances.
\ No newline at end of file
+-- Note: For "insufficient funds" and "account does not exist" scenarios, ledger entries are not created as these transactions would fail validation checks before affecting account balances.
+
+-- Bring the account_balances projection in line with the seeded ledger entries
+SELECT rebuild_account_balances();
@@ -0,0 +1,4 @@
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS exponent;
+ALTER TABLE ledger_entries DROP COLUMN IF EXISTS currency;
+ALTER TABLE transactions DROP COLUMN IF EXISTS currency;
+ALTER TABLE accounts DROP COLUMN IF EXISTS currency;
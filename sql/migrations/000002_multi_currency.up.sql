@@ -0,0 +1,8 @@
+-- Every account is denominated in a single ISO 4217 currency
+ALTER TABLE accounts ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+
+-- Transactions and ledger entries carry the currency they were posted in, along with
+-- the minor-unit exponent so that amounts can be interpreted without a lookup table
+ALTER TABLE transactions ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+ALTER TABLE ledger_entries ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+ALTER TABLE ledger_entries ADD COLUMN exponent SMALLINT NOT NULL DEFAULT 2;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_ledger_entries_account_id_created_at;
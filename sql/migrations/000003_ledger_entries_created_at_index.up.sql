@@ -0,0 +1,2 @@
+-- Point-in-time balances sum an account's ledger entries up to a timestamp
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_id_created_at ON ledger_entries(account_id, created_at);
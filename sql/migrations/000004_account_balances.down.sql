@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS accounts_create_balance_trigger_fn ON accounts;
+DROP FUNCTION IF EXISTS create_account_balance_function();
+DROP FUNCTION IF EXISTS rebuild_account_balances(BOOLEAN);
+DROP TABLE IF EXISTS account_balances;
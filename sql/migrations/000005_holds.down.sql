@@ -0,0 +1,45 @@
+DROP TABLE IF EXISTS holds;
+
+-- Restore the rebuild function from before holds existed
+DROP FUNCTION IF EXISTS rebuild_account_balances(BOOLEAN);
+CREATE OR REPLACE FUNCTION rebuild_account_balances(dry_run BOOLEAN DEFAULT FALSE)
+    RETURNS TABLE (balance_account_id TEXT, projected_posted BIGINT, ledger_posted BIGINT)
+    LANGUAGE 'plpgsql'
+AS $BODY$
+BEGIN
+    IF NOT dry_run THEN
+        -- Block new postings so the ledger cannot move while it is being summed
+        LOCK TABLE ledger_entries IN SHARE MODE;
+    END IF;
+
+    CREATE TEMP TABLE ledger_balances AS
+    SELECT a.id AS account_id, a.currency,
+           COALESCE(SUM(CASE WHEN le.direction = 'credit' THEN le.amount ELSE -le.amount END), 0)::BIGINT AS posted
+    FROM accounts a
+    LEFT JOIN ledger_entries le ON le.account_id = a.id
+    GROUP BY a.id, a.currency;
+
+    RETURN QUERY
+    SELECT l.account_id, COALESCE(b.posted, 0), l.posted
+    FROM ledger_balances l
+    LEFT JOIN account_balances b ON b.account_id = l.account_id
+    WHERE b.account_id IS NULL OR b.posted <> l.posted
+    ORDER BY l.account_id;
+
+    IF NOT dry_run THEN
+        INSERT INTO account_balances (account_id, currency)
+        SELECT l.account_id, l.currency FROM ledger_balances l
+        ON CONFLICT (account_id) DO NOTHING;
+
+        UPDATE account_balances b
+        SET posted = l.posted,
+            available = l.posted - b.pending,
+            version = b.version + 1,
+            updated_at = CURRENT_TIMESTAMP
+        FROM ledger_balances l
+        WHERE b.account_id = l.account_id AND b.posted <> l.posted;
+    END IF;
+
+    DROP TABLE ledger_balances;
+END;
+$BODY$;
@@ -0,0 +1,74 @@
+-- Holds reserve funds on the debited account without posting to the ledger. A hold is
+-- created as 'pending' and is resolved exactly once as 'posted' (captured, possibly for
+-- less than the held amount), 'voided' or 'expired'.
+CREATE TABLE holds (
+    id TEXT PRIMARY KEY,
+    debit_account_id TEXT NOT NULL REFERENCES accounts(id),
+    credit_account_id TEXT NOT NULL REFERENCES accounts(id),
+    amount BIGINT NOT NULL,
+    captured_amount BIGINT NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL,
+    status TEXT NOT NULL, -- e.g., 'pending', 'posted', 'voided', 'expired'
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    transaction_id TEXT REFERENCES transactions(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_by TEXT NOT NULL DEFAULT 'system',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_by TEXT NOT NULL DEFAULT 'system',
+    history JSONB[] DEFAULT '{}'
+);
+
+CREATE INDEX idx_holds_debit_account_id ON holds(debit_account_id);
+CREATE INDEX idx_holds_pending_expires_at ON holds(expires_at) WHERE status = 'pending';
+
+CREATE TRIGGER update_holds_updated_at BEFORE UPDATE ON holds FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER holds_history_trigger_fn BEFORE UPDATE ON holds FOR EACH ROW EXECUTE FUNCTION history_trigger_function();
+
+-- Rebuilding balances now also recomputes pending amounts from unresolved holds
+DROP FUNCTION IF EXISTS rebuild_account_balances(BOOLEAN);
+CREATE OR REPLACE FUNCTION rebuild_account_balances(dry_run BOOLEAN DEFAULT FALSE)
+    RETURNS TABLE (balance_account_id TEXT, projected_posted BIGINT, ledger_posted BIGINT, projected_pending BIGINT, held_pending BIGINT)
+    LANGUAGE 'plpgsql'
+AS $BODY$
+BEGIN
+    IF NOT dry_run THEN
+        -- Block new postings and holds so balances cannot move while they are being summed
+        LOCK TABLE ledger_entries IN SHARE MODE;
+        LOCK TABLE holds IN SHARE MODE;
+    END IF;
+
+    CREATE TEMP TABLE ledger_balances AS
+    SELECT a.id AS account_id, a.currency,
+           COALESCE((SELECT SUM(CASE WHEN le.direction = 'credit' THEN le.amount ELSE -le.amount END)
+                     FROM ledger_entries le WHERE le.account_id = a.id), 0)::BIGINT AS posted,
+           COALESCE((SELECT SUM(h.amount)
+                     FROM holds h WHERE h.debit_account_id = a.id AND h.status = 'pending'), 0)::BIGINT AS pending
+    FROM accounts a;
+
+    RETURN QUERY
+    SELECT l.account_id, COALESCE(b.posted, 0), l.posted, COALESCE(b.pending, 0), l.pending
+    FROM ledger_balances l
+    LEFT JOIN account_balances b ON b.account_id = l.account_id
+    WHERE b.account_id IS NULL OR b.posted <> l.posted OR b.pending <> l.pending OR b.available <> l.posted - l.pending
+    ORDER BY l.account_id;
+
+    IF NOT dry_run THEN
+        INSERT INTO account_balances (account_id, currency)
+        SELECT l.account_id, l.currency FROM ledger_balances l
+        ON CONFLICT (account_id) DO NOTHING;
+
+        UPDATE account_balances b
+        SET posted = l.posted,
+            pending = l.pending,
+            available = l.posted - l.pending,
+            version = b.version + 1,
+            updated_at = CURRENT_TIMESTAMP
+        FROM ledger_balances l
+        WHERE b.account_id = l.account_id
+          AND (b.posted <> l.posted OR b.pending <> l.pending OR b.available <> l.posted - l.pending);
+    END IF;
+
+    DROP TABLE ledger_balances;
+END;
+$BODY$;
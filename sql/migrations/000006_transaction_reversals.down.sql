@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS transactions_history_trigger_fn ON transactions;
+CREATE TRIGGER transactions_history_trigger_fn BEFORE UPDATE ON users FOR EACH ROW EXECUTE PROCEDURE history_trigger_function ();
+
+DROP INDEX IF EXISTS idx_transactions_reverses_transaction_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reversed_amount;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reverses_transaction_id;
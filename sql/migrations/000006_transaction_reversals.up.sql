@@ -0,0 +1,11 @@
+-- A reversal is a new transaction whose ledger entries mirror (part of) an earlier one.
+-- The original keeps a running total of how much of it has been reversed.
+ALTER TABLE transactions ADD COLUMN reverses_transaction_id TEXT REFERENCES transactions(id);
+ALTER TABLE transactions ADD COLUMN reversed_amount BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_transactions_reverses_transaction_id ON transactions(reverses_transaction_id);
+
+-- The history trigger for transactions was attached to the users table, so status changes
+-- on a transaction were never recorded
+DROP TRIGGER IF EXISTS transactions_history_trigger_fn ON users;
+CREATE TRIGGER transactions_history_trigger_fn BEFORE UPDATE ON transactions FOR EACH ROW EXECUTE FUNCTION history_trigger_function();
@@ -0,0 +1,4 @@
+-- Multi-currency journal entries cannot be represented once currency is required again;
+-- their ledger entries keep their own currencies
+UPDATE transactions SET currency = 'USD' WHERE currency IS NULL;
+ALTER TABLE transactions ALTER COLUMN currency SET NOT NULL;
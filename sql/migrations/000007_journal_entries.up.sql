@@ -0,0 +1,4 @@
+-- A journal entry may move several currencies at once, each balanced on its own. Such an
+-- entry has no single currency: its transactions row has a NULL currency and an amount of
+-- 0, and each ledger entry carries its own currency.
+ALTER TABLE transactions ALTER COLUMN currency DROP NOT NULL;
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS accounts_history_trigger_fn ON accounts;
+CREATE TRIGGER accounts_history_trigger_fn BEFORE UPDATE ON users FOR EACH ROW EXECUTE PROCEDURE history_trigger_function ();
+
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_account_state_check;
@@ -0,0 +1,9 @@
+-- Accounts are open, frozen or closed. Existing rows are not validated so that accounts
+-- created with another state before it was enforced keep working until they are migrated.
+ALTER TABLE accounts ADD CONSTRAINT accounts_account_state_check
+    CHECK (account_state IN ('open', 'frozen', 'closed')) NOT VALID;
+
+-- The history trigger for accounts was attached to the users table, so state changes on an
+-- account were never recorded
+DROP TRIGGER IF EXISTS accounts_history_trigger_fn ON users;
+CREATE TRIGGER accounts_history_trigger_fn BEFORE UPDATE ON accounts FOR EACH ROW EXECUTE FUNCTION history_trigger_function();
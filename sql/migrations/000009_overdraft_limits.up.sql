@@ -0,0 +1,6 @@
+-- How far below zero an account's available balance may go, in minor units. NULL lets the
+-- account go negative without limit, which external and settlement accounts need.
+ALTER TABLE accounts ADD COLUMN overdraft_limit BIGINT DEFAULT 0 CHECK (overdraft_limit IS NULL OR overdraft_limit >= 0);
+
+-- The external account created for every user is its only credit account
+UPDATE accounts SET overdraft_limit = NULL WHERE account_type = 'credit';
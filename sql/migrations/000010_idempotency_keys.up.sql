@@ -0,0 +1,15 @@
+-- Idempotency keys of money-moving requests, along with a fingerprint of the request they
+-- were first used with and the response that was returned so that retries can be replayed
+CREATE TABLE idempotency_keys (
+    caller TEXT NOT NULL,
+    key TEXT NOT NULL,
+    method TEXT NOT NULL,
+    fingerprint BYTEA NOT NULL,
+    response BYTEA, -- NULL while the original request is in flight
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (caller, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
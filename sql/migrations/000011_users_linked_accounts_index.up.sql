@@ -0,0 +1,2 @@
+-- Transfers may only credit internal accounts, which are looked up by the user they belong to
+CREATE INDEX IF NOT EXISTS idx_users_int_ledger_account_id ON users(int_ledger_account_id);
@@ -0,0 +1,4 @@
+-- Users are active until they are deactivated; deactivated users keep their accounts and
+-- history but can no longer move money
+ALTER TABLE users ADD COLUMN IF NOT EXISTS user_state TEXT NOT NULL DEFAULT 'active'
+    CONSTRAINT users_user_state_check CHECK (user_state IN ('active', 'deactivated'));
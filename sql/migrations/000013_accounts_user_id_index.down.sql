@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_accounts_user_id;
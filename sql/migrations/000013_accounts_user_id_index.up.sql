@@ -0,0 +1,2 @@
+-- Accounts are listed by the user they belong to
+CREATE INDEX IF NOT EXISTS idx_accounts_user_id ON accounts(user_id, id);
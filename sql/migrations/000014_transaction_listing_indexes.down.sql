@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_transactions_created_by;
+DROP INDEX IF EXISTS idx_transactions_created_at;
+DROP INDEX IF EXISTS idx_ledger_entries_transaction_id;
+DROP INDEX IF EXISTS idx_ledger_entries_account_id_transaction_id;
@@ -0,0 +1,7 @@
+-- An account's transactions are listed in transaction id order, in either direction
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_id_transaction_id ON ledger_entries(account_id, transaction_id);
+-- Legs are looked up by transaction, e.g. to filter on the counterparty account
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries(transaction_id);
+-- Reconciliation filters transactions by when they were posted and who posted them
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);
+CREATE INDEX IF NOT EXISTS idx_transactions_created_by ON transactions(created_by);
@@ -0,0 +1,3 @@
+-- Transactions are paged through by (created_at, id), in either direction
+DROP INDEX IF EXISTS idx_transactions_created_at;
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at_id ON transactions(created_at, id);
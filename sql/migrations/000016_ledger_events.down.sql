@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS ledger_event_checkpoints;
+DROP TRIGGER IF EXISTS ledger_events_position_trigger ON ledger_events;
+DROP FUNCTION IF EXISTS assign_ledger_event_position();
+DROP TABLE IF EXISTS ledger_events;
@@ -0,0 +1,50 @@
+-- ledger_events is a transactional outbox: every change to the ledger records an event in
+-- the same database transaction as the change, and the relay publishes them to sinks
+CREATE TABLE ledger_events (
+    id BIGSERIAL PRIMARY KEY,
+    -- position orders events by when their transaction committed. It is assigned at commit,
+    -- so it is only NULL inside the transaction that wrote the event.
+    position BIGINT UNIQUE,
+    event_type TEXT NOT NULL,
+    -- aggregate_id is the transaction or account the event is about
+    aggregate_id TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE SEQUENCE ledger_events_position_seq OWNED BY ledger_events.position;
+
+-- Positions are handed out at commit under a lock that is held until the commit completes,
+-- so they follow commit order and a reader that has seen position N can never later find a
+-- new event below N. Taking the lock at commit rather than when the event is written keeps
+-- postings from queueing on it while they hold locks on account balances.
+--
+-- The cost is that every transaction that records an event, which is every posting and
+-- account state change, commits one at a time: the lock is held from the deferred trigger
+-- through the commit's WAL flush. Throughput of money-moving commits is therefore bounded by
+-- roughly one commit (an fsync, under synchronous_commit) at a time across the whole
+-- database, whatever the accounts involved. Everything before the commit still runs
+-- concurrently. If that bound is reached, positions would have to be assigned after commit
+-- instead, e.g. by the relay, at the price of readers tracking transaction visibility.
+CREATE FUNCTION assign_ledger_event_position()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('ledger_events'));
+    UPDATE ledger_events SET position = nextval('ledger_events_position_seq') WHERE id = NEW.id;
+    RETURN NULL;
+END;
+$$;
+
+CREATE CONSTRAINT TRIGGER ledger_events_position_trigger
+    AFTER INSERT ON ledger_events
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION assign_ledger_event_position();
+
+-- ledger_event_checkpoints holds the position of the last event each sink has received
+CREATE TABLE ledger_event_checkpoints (
+    sink TEXT PRIMARY KEY,
+    position BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
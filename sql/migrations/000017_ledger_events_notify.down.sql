@@ -0,0 +1,10 @@
+CREATE OR REPLACE FUNCTION assign_ledger_event_position()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('ledger_events'));
+    UPDATE ledger_events SET position = nextval('ledger_events_position_seq') WHERE id = NEW.id;
+    RETURN NULL;
+END;
+$$;
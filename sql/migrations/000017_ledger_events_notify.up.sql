@@ -0,0 +1,14 @@
+-- Notify listeners on the ledger_events channel when events are committed, so watchers can
+-- read them straight away instead of polling. Identical notifications are folded into one
+-- per transaction, and they are only delivered once the transaction commits.
+CREATE OR REPLACE FUNCTION assign_ledger_event_position()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('ledger_events'));
+    UPDATE ledger_events SET position = nextval('ledger_events_position_seq') WHERE id = NEW.id;
+    PERFORM pg_notify('ledger_events', '');
+    RETURN NULL;
+END;
+$$;
@@ -0,0 +1,42 @@
+-- webhook_endpoints are the URLs users have registered to be sent the ledger events about
+-- their accounts
+CREATE TABLE webhook_endpoints (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id),
+    url TEXT NOT NULL,
+    -- secret signs every delivery to the endpoint
+    secret TEXT NOT NULL,
+    -- event_types the endpoint is sent; empty means every type
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- deleted_at is set when the endpoint is deleted; its deliveries are kept for the log
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_user_id ON webhook_endpoints(user_id) WHERE deleted_at IS NULL;
+
+-- webhook_deliveries is one ledger event to be sent to one endpoint, and the outcome of the
+-- last attempt to send it. Its id is sent with every attempt, so receivers can tell a
+-- retried delivery from a new one.
+CREATE TABLE webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    endpoint_id TEXT NOT NULL REFERENCES webhook_endpoints(id),
+    event_id BIGINT NOT NULL REFERENCES ledger_events(id),
+    event_type TEXT NOT NULL,
+    delivery_state TEXT NOT NULL DEFAULT 'pending' CHECK (delivery_state IN ('pending', 'succeeded', 'dead')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    -- next_attempt_at is when a pending delivery is due; it is also pushed back while an
+    -- attempt is in flight, so that no other worker picks the delivery up meanwhile
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    -- last_status_code is the HTTP status of the last response, NULL if there was none
+    last_status_code INTEGER,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    -- An event is relayed at least once, but delivered to an endpoint only once
+    CONSTRAINT webhook_deliveries_endpoint_event_key UNIQUE (endpoint_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE delivery_state = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_id ON webhook_deliveries(endpoint_id, id);
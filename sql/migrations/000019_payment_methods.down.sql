@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_transactions_external_payment_method_id;
+DROP INDEX IF EXISTS idx_payment_methods_user_id;
+
+ALTER TABLE payment_methods DROP COLUMN IF EXISTS removed_at;
+ALTER TABLE payment_methods ALTER COLUMN is_verified DROP NOT NULL;
+
+-- User IDs cannot be stored as integers, so the payment methods, and the references
+-- transactions hold to them, are dropped
+UPDATE transactions SET external_payment_method_id = NULL WHERE external_payment_method_id IS NOT NULL;
+DELETE FROM payment_methods;
+ALTER TABLE payment_methods DROP CONSTRAINT IF EXISTS payment_methods_user_id_fkey;
+ALTER TABLE payment_methods ALTER COLUMN user_id TYPE INTEGER USING NULL;
@@ -0,0 +1,16 @@
+-- payment_methods.user_id was an INTEGER, so a payment method could never refer to a user.
+-- Nothing wrote payment methods before, so existing rows are converted as they are and the
+-- foreign key fails loudly if any of them does not belong to a user.
+ALTER TABLE payment_methods ALTER COLUMN user_id TYPE TEXT USING user_id::TEXT;
+ALTER TABLE payment_methods ADD CONSTRAINT payment_methods_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+
+UPDATE payment_methods SET is_verified = FALSE WHERE is_verified IS NULL;
+ALTER TABLE payment_methods ALTER COLUMN is_verified SET NOT NULL;
+
+-- removed_at is set when a user removes a payment method. The row is kept because the
+-- transactions made with it still refer to it.
+ALTER TABLE payment_methods ADD COLUMN removed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_payment_methods_user_id ON payment_methods(user_id) WHERE removed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_external_payment_method_id ON transactions(external_payment_method_id)
+    WHERE external_payment_method_id IS NOT NULL;
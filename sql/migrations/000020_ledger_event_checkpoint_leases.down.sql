@@ -0,0 +1 @@
+ALTER TABLE ledger_event_checkpoints DROP COLUMN IF EXISTS leased_until;
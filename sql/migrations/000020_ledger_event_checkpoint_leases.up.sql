@@ -0,0 +1,4 @@
+-- A relay leases a sink's checkpoint while it publishes a batch, instead of holding a row
+-- lock, so that no database transaction stays open across the publish. Only one relay
+-- publishes to a sink at a time; a lease that was not given back lapses at leased_until.
+ALTER TABLE ledger_event_checkpoints ADD COLUMN leased_until TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,29 @@
+DROP INDEX IF EXISTS ledger_events_unpositioned_idx;
+DROP TRIGGER IF EXISTS ledger_events_notify_trigger ON ledger_events;
+DROP FUNCTION IF EXISTS notify_ledger_events();
+
+-- Events committed since positions were last given out get them now, in the order they
+-- were written
+UPDATE ledger_events e SET position = unpositioned.position
+FROM (
+    SELECT id, nextval('ledger_events_position_seq') AS position
+    FROM (SELECT id FROM ledger_events WHERE position IS NULL ORDER BY id) ordered
+) unpositioned
+WHERE e.id = unpositioned.id;
+
+CREATE FUNCTION assign_ledger_event_position()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('ledger_events'));
+    UPDATE ledger_events SET position = nextval('ledger_events_position_seq') WHERE id = NEW.id;
+    PERFORM pg_notify('ledger_events', '');
+    RETURN NULL;
+END;
+$$;
+
+CREATE CONSTRAINT TRIGGER ledger_events_position_trigger
+    AFTER INSERT ON ledger_events
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION assign_ledger_event_position();
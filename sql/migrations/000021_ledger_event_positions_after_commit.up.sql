@@ -0,0 +1,25 @@
+-- Positions are no longer handed out at commit, which made every transaction that records
+-- an event commit one at a time. Events are committed without a position, and readers of the
+-- outbox give committed events their positions afterwards, one reader at a time, so
+-- positions still never go backwards: an event that commits later is always given a higher
+-- position than the ones that are already visible.
+DROP TRIGGER IF EXISTS ledger_events_position_trigger ON ledger_events;
+DROP FUNCTION IF EXISTS assign_ledger_event_position();
+
+-- Notify listeners on the ledger_events channel when events are committed, so they can
+-- give them positions and read them straight away instead of polling
+CREATE FUNCTION notify_ledger_events()
+    RETURNS trigger
+    LANGUAGE plpgsql
+AS $$
+BEGIN
+    PERFORM pg_notify('ledger_events', '');
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER ledger_events_notify_trigger
+    AFTER INSERT ON ledger_events
+    FOR EACH STATEMENT EXECUTE FUNCTION notify_ledger_events();
+
+CREATE INDEX ledger_events_unpositioned_idx ON ledger_events (id) WHERE position IS NULL;